@@ -0,0 +1,108 @@
+package jsonpath
+
+import (
+	"container/list"
+	"fmt"
+	"sync"
+)
+
+const defaultCacheSize = 128
+
+// Compiled paths used by the package-level functions, keyed by the path and
+// the options it was compiled with
+var cache = newPathCache(defaultCacheSize)
+
+type pathCache struct {
+	mu      sync.Mutex
+	size    int
+	entries map[string]*list.Element
+	order   *list.List
+}
+
+type cacheEntry struct {
+	key      string
+	compiled *Compiled
+}
+
+func newPathCache(size int) *pathCache {
+	return &pathCache{
+		size:    size,
+		entries: map[string]*list.Element{},
+		order:   list.New(),
+	}
+}
+
+// Sets the number of compiled paths cached by the package-level functions.
+// A size of 0 disables the cache.
+func SetCacheSize(n int) {
+	if n < 0 {
+		n = 0
+	}
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	cache.size = n
+	cache.evict()
+}
+
+// Removes all compiled paths from the cache
+func ClearCache() {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	cache.entries = map[string]*list.Element{}
+	cache.order.Init()
+}
+
+// Returns a cached compiled path, compiling and caching it if needed
+func compileCached(path string, options ...func(*Compiled)) (*Compiled, error) {
+	// options are functions, so the key is built from the state they produce
+	var applied Compiled
+	for _, option := range options {
+		option(&applied)
+	}
+	key := fmt.Sprintf("%s\x00%+v", path, applied)
+
+	if compiled, ok := cache.get(key); ok {
+		return compiled, nil
+	}
+	compiled, err := Compile(path, options...)
+	if err != nil {
+		return nil, err
+	}
+	cache.add(key, compiled)
+	return compiled, nil
+}
+
+func (p *pathCache) get(key string) (*Compiled, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	elem, ok := p.entries[key]
+	if !ok {
+		return nil, false
+	}
+	p.order.MoveToFront(elem)
+	return elem.Value.(*cacheEntry).compiled, true
+}
+
+func (p *pathCache) add(key string, compiled *Compiled) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.size == 0 {
+		return
+	}
+	if elem, ok := p.entries[key]; ok {
+		p.order.MoveToFront(elem)
+		elem.Value.(*cacheEntry).compiled = compiled
+		return
+	}
+	p.entries[key] = p.order.PushFront(&cacheEntry{key, compiled})
+	p.evict()
+}
+
+// Removes the least recently used paths until the cache fits its size
+func (p *pathCache) evict() {
+	for p.order.Len() > p.size {
+		elem := p.order.Back()
+		p.order.Remove(elem)
+		delete(p.entries, elem.Value.(*cacheEntry).key)
+	}
+}
@@ -0,0 +1,86 @@
+package jsonpath
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestCache(t *testing.T) {
+	defer SetCacheSize(defaultCacheSize)
+
+	t.Run("reuses-compiled-path", func(t *testing.T) {
+		ClearCache()
+		first, _ := compileCached("key1.key2")
+		second, _ := compileCached("key1.key2")
+		if first != second {
+			t.Errorf("compileCached() returned a new path for a cached path")
+		}
+	})
+
+	t.Run("options-in-key", func(t *testing.T) {
+		ClearCache()
+		plain, _ := compileCached("key1.key2")
+		strict, _ := compileCached("key1.key2", EnableStrictPaths())
+		json, _ := compileCached("key1.key2", UseStructTag("json"))
+		yaml, _ := compileCached("key1.key2", UseStructTag("yaml"))
+		if plain == strict || plain == json || json == yaml {
+			t.Errorf("compileCached() shared a path compiled with different options")
+		}
+		if !strict.strictPaths || yaml.structTag != "yaml" {
+			t.Errorf("compileCached() returned a path without its options")
+		}
+	})
+
+	t.Run("evicts-least-recently-used", func(t *testing.T) {
+		ClearCache()
+		SetCacheSize(2)
+		first, _ := compileCached("key1")
+		compileCached("key2")
+		compileCached("key1")
+		compileCached("key3")
+		if got, _ := compileCached("key1"); got != first {
+			t.Errorf("compileCached() evicted the most recently used path")
+		}
+		if len(cache.entries) != 2 || cache.order.Len() != 2 {
+			t.Errorf("cache size = %d, want %d", len(cache.entries), 2)
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		SetCacheSize(0)
+		first, _ := compileCached("key1")
+		second, _ := compileCached("key1")
+		if first == second {
+			t.Errorf("compileCached() cached a path with a cache size of 0")
+		}
+	})
+
+	t.Run("errors-not-cached", func(t *testing.T) {
+		SetCacheSize(defaultCacheSize)
+		ClearCache()
+		_, err := compileCached("key1[")
+		if err == nil {
+			t.Errorf("compileCached() error = nil, want error")
+		}
+		if len(cache.entries) != 0 {
+			t.Errorf("cache size = %d, want %d", len(cache.entries), 0)
+		}
+	})
+
+	t.Run("concurrent", func(t *testing.T) {
+		SetCacheSize(4)
+		var wg sync.WaitGroup
+		for i := 0; i < 100; i += 1 {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				paths := []string{"key1.key2", "key3.map.key1", "key4[0]", "key2.array[1]", "key5.int"}
+				_, err := Get(getData(), paths[i%len(paths)])
+				if err != nil {
+					t.Errorf("Get() error = %v", err)
+				}
+			}(i)
+		}
+		wg.Wait()
+	})
+}
@@ -0,0 +1,10 @@
+module github.com/kivera-io/jsonpath/cmd/jsonpath
+
+go 1.20
+
+require (
+	github.com/kivera-io/jsonpath v0.0.0
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+replace github.com/kivera-io/jsonpath => ../..
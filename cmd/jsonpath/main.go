@@ -1,14 +1,19 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
 	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/kivera-io/jsonpath"
+	"gopkg.in/yaml.v3"
 )
 
 func quit(e error) {
@@ -20,16 +25,217 @@ func quit(e error) {
 	os.Exit(1)
 }
 
+// Decodes JSON keeping numbers as json.Number, so large integers are not
+// rounded by converting them to float64
+func unmarshal(data []byte, v interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	err := dec.Decode(v)
+	if err != nil {
+		return err
+	}
+	if dec.More() {
+		return errors.New("invalid character after top-level value")
+	}
+	return nil
+}
+
+// Decodes YAML, converting maps with non-string keys to map[string]interface{}
+// so they can be queried in the same way as JSON
+func unmarshalYAML(data []byte, v *interface{}) error {
+	err := yaml.Unmarshal(data, v)
+	if err != nil {
+		return err
+	}
+	*v = normalizeYAML(*v)
+	return nil
+}
+
+func normalizeYAML(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		result := make(map[string]interface{}, len(v))
+		for key, val := range v {
+			result[fmt.Sprint(key)] = normalizeYAML(val)
+		}
+		return result
+	case map[string]interface{}:
+		for key, val := range v {
+			v[key] = normalizeYAML(val)
+		}
+	case []interface{}:
+		for i, val := range v {
+			v[i] = normalizeYAML(val)
+		}
+	}
+	return value
+}
+
+// Converts json.Numbers to int64 or float64, which YAML encodes as numbers
+// rather than strings
+func yamlNumbers(value interface{}) interface{} {
+	switch v := value.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		if f, err := v.Float64(); err == nil {
+			return f
+		}
+	case map[string]interface{}:
+		for key, val := range v {
+			v[key] = yamlNumbers(val)
+		}
+	case []interface{}:
+		for i, val := range v {
+			v[i] = yamlNumbers(val)
+		}
+	}
+	return value
+}
+
+// Returns true if the file has a YAML extension
+func isYAMLFile(file string) bool {
+	ext := strings.ToLower(filepath.Ext(file))
+	return ext == ".yaml" || ext == ".yml"
+}
+
+// A single operation in a patch file
+type patchOp struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value"`
+}
+
+func readPatch(file string) ([]patchOp, error) {
+	f, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var ops []patchOp
+	err = unmarshal(f, &ops)
+	if err != nil {
+		return nil, fmt.Errorf("invalid patch file: %w", err)
+	}
+	return ops, nil
+}
+
+// Applies each operation to the data in order, stopping at the first error
+func applyPatch(data interface{}, ops []patchOp, options ...func(*jsonpath.Compiled)) error {
+	for i, op := range ops {
+		var err error
+		switch op.Op {
+		case "set":
+			err = jsonpath.Set(data, op.Path, op.Value, options...)
+		case "append":
+			err = jsonpath.Append(data, op.Path, op.Value, options...)
+		default:
+			err = fmt.Errorf("unknown operation (%s)", op.Op)
+		}
+		if err != nil {
+			return fmt.Errorf("patch operation %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// Deletes the values matched by the query, returning the resulting document.
+// The document is passed by pointer so elements can be deleted from a root
+// array.
+func deleteValues(data interface{}, query string, options ...func(*jsonpath.Compiled)) (interface{}, error) {
+	err := jsonpath.Delete(&data, query, options...)
+	return data, err
+}
+
+// Applies process to each document in a stream of JSON Lines, writing one
+// result per line. Blank lines are skipped. Errors are reported with the line
+// number, and either stop the stream or are written to errOut when
+// skipErrors is set.
+func processLines(r io.Reader, w io.Writer, errOut io.Writer, skipErrors bool, process func(interface{}) (interface{}, error)) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 64*1024*1024)
+	var line int
+	for scanner.Scan() {
+		line += 1
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		output, err := processLine(scanner.Bytes(), process)
+		if err != nil {
+			err = fmt.Errorf("line %d: %w", line, err)
+			if !skipErrors {
+				return err
+			}
+			fmt.Fprintln(errOut, "error:", err)
+			continue
+		}
+		fmt.Fprintln(w, string(output))
+	}
+	return scanner.Err()
+}
+
+func processLine(line []byte, process func(interface{}) (interface{}, error)) ([]byte, error) {
+	var data interface{}
+	err := unmarshal(line, &data)
+	if err != nil {
+		return nil, err
+	}
+	result, err := process(data)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(result)
+}
+
+// Gets the result of each query, keyed by the query or by the name at the
+// same position in names. With merge, the results are instead returned in a
+// single slice, with the values of multi-match queries added individually.
+func getAll(data interface{}, queries []string, names []string, merge bool, options ...func(*jsonpath.Compiled)) (interface{}, error) {
+	if len(names) > 0 && len(names) != len(queries) {
+		return nil, fmt.Errorf("got %d names for %d queries", len(names), len(queries))
+	}
+	merged := []interface{}{}
+	results := map[string]interface{}{}
+	for i, query := range queries {
+		c, err := jsonpath.Compile(query, options...)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", query, err)
+		}
+		result, err := c.Get(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", query, err)
+		}
+		if merge {
+			if values, ok := result.([]interface{}); ok && c.IsMulti() {
+				merged = append(merged, values...)
+			} else {
+				merged = append(merged, result)
+			}
+			continue
+		}
+		key := query
+		if len(names) > 0 {
+			key = names[i]
+		}
+		results[key] = result
+	}
+	if merge {
+		return merged, nil
+	}
+	return results, nil
+}
+
 func main() {
-	var query string
+	var queries []string
 	var input string
 	var file string
 	var set string
+	var patch string
 
 	flag.Usage = func() {
 		fmt.Printf("An example implementation of the JSONPath package.\n\n")
-		fmt.Printf("JSON data can be provided using the command line options or through stdin.\n\n")
-		fmt.Printf("./jsonpath <query> [ -option, ... ]\n\n")
+		fmt.Printf("JSON or YAML data can be provided using the command line options or through stdin.\n\n")
+		fmt.Printf("./jsonpath <query> [ <query>, ... ] [ -option, ... ]\n\n")
 		fmt.Printf("options:\n\n")
 		flag.PrintDefaults()
 	}
@@ -37,25 +243,113 @@ func main() {
 	flag.StringVar(&input, "data", "", "A JSON string to process")
 	flag.StringVar(&file, "file", "", "A JSON file to process")
 	flag.StringVar(&set, "set", "", "A value to set using the query")
+	flag.StringVar(&patch, "patch", "", "A JSON file of {op, path, value} operations to apply, where op is set or append")
+	del := flag.Bool("delete", false, "Delete the values matched by the query and print the resulting document. Cannot be used with -set or -patch")
 	indent := flag.Int("indent", 0, "Indentation to use when printing the result")
 	strict := flag.Bool("strict", false, "Only allow setting values on existing paths")
+	jsonl := flag.Bool("jsonl", false, "Process each line of the input as a separate JSON document, printing one result per line")
+	skipErrors := flag.Bool("skip-errors", false, "With -jsonl, report errors for a line and continue instead of stopping")
+	yamlIn := flag.Bool("yaml", false, "Read the input as YAML. Files with a .yaml or .yml extension are read as YAML by default")
+	raw := flag.Bool("raw", false, "Print string results without quotes, and a list of strings with one per line")
+	yamlOut := flag.Bool("yaml-out", false, "Print the result as YAML instead of JSON")
+	name := flag.String("name", "", "Comma separated names to key the results of multiple queries by, in the same order as the queries")
+	merge := flag.Bool("merge", false, "Print the results of multiple queries as a single list instead of an object")
+	exists := flag.Bool("exists", false, "Print nothing and exit with 0 if the query matches a value, or 1 if it does not. Cannot be used with -set, -patch, -delete or -jsonl")
 	flag.Parse()
 
-	args := flag.Args()
-	if len(args) > 0 {
-		query = args[0]
+	// queries and options can be mixed
+	for args := flag.Args(); len(args) > 0; args = flag.Args() {
+		queries = append(queries, args[0])
 		flag.CommandLine.Parse(args[1:])
 	}
 
-	if query == "" {
+	var query string
+	if len(queries) > 0 {
+		query = queries[0]
+	}
+	if query == "" && patch == "" {
 		quit(errors.New("no query provided"))
 	}
+	if len(queries) > 1 && (set != "" || patch != "" || *del || *exists) {
+		quit(errors.New("multiple queries can only be used to get values"))
+	}
+	var names []string
+	if *name != "" {
+		names = strings.Split(*name, ",")
+	}
+	if *exists && (set != "" || patch != "" || *del || *jsonl) {
+		quit(errors.New("-exists cannot be used with -set, -patch, -delete or -jsonl"))
+	}
+	if *del && (set != "" || patch != "") {
+		quit(errors.New("-delete cannot be used with -set or -patch"))
+	}
+
+	options := []func(*jsonpath.Compiled){}
+	if *strict {
+		options = append(options, jsonpath.EnableStrictPaths())
+	}
+
+	var ops []patchOp
+	if patch != "" {
+		var err error
+		ops, err = readPatch(patch)
+		if err != nil {
+			quit(err)
+		}
+	}
+	process := func(data interface{}) (interface{}, error) {
+		if patch != "" {
+			err := applyPatch(data, ops, options...)
+			return data, err
+		}
+		if *del {
+			return deleteValues(data, query, options...)
+		}
+		if set != "" {
+			var val interface{}
+			err := unmarshal([]byte(set), &val)
+			if err != nil {
+				val = set
+			}
+			err = jsonpath.Set(data, query, val, options...)
+			return data, err
+		}
+		if len(queries) > 1 || len(names) > 0 || *merge {
+			return getAll(data, queries, names, *merge, options...)
+		}
+		return jsonpath.Get(data, query, options...)
+	}
 
 	fi, err := os.Stdin.Stat()
 	if err != nil {
 		quit(err)
 	}
-	if fi.Mode()&os.ModeCharDevice == 0 {
+	piped := fi.Mode()&os.ModeCharDevice == 0
+
+	if *jsonl {
+		var r io.Reader
+		if piped {
+			r = os.Stdin
+		} else if input != "" {
+			r = strings.NewReader(input)
+		} else if file != "" {
+			f, err := os.Open(file)
+			if err != nil {
+				quit(err)
+			}
+			defer f.Close()
+			r = f
+		} else {
+			quit(errors.New("no JSON input provided"))
+		}
+		err = processLines(r, os.Stdout, os.Stderr, *skipErrors, process)
+		if err != nil {
+			quit(err)
+		}
+		return
+	}
+
+	if piped {
 		piped, err := io.ReadAll(os.Stdin)
 		if err != nil {
 			quit(err)
@@ -63,9 +357,16 @@ func main() {
 		input = string(piped)
 	}
 
+	decode := func(data []byte, v *interface{}) error {
+		return unmarshal(data, v)
+	}
+	if *yamlIn || (input == "" && isYAMLFile(file)) {
+		decode = unmarshalYAML
+	}
+
 	var data interface{}
 	if input != "" {
-		err := json.Unmarshal([]byte(input), &data)
+		err := decode([]byte(input), &data)
 		if err != nil {
 			quit(err)
 		}
@@ -74,7 +375,7 @@ func main() {
 		if err != nil {
 			quit(err)
 		}
-		err = json.Unmarshal(f, &data)
+		err = decode(f, &data)
 		if err != nil {
 			quit(err)
 		}
@@ -82,37 +383,70 @@ func main() {
 		quit(errors.New("no JSON input provided"))
 	}
 
-	c, err := jsonpath.Compile(query)
+	if *exists {
+		found, err := jsonpath.Exists(data, query, options...)
+		if err != nil {
+			quit(err)
+		}
+		if !found {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	result, err := process(data)
 	if err != nil {
 		quit(err)
 	}
-	if *strict {
-		c.EnableStrictPaths()
+
+	if *raw {
+		if output, ok := rawOutput(result); ok {
+			fmt.Println(output)
+			return
+		}
 	}
+	printResult(result, *indent, *yamlOut)
+}
 
-	var result interface{}
-	if set != "" {
-		var val interface{}
-		err = json.Unmarshal([]byte(set), &val)
-		if err != nil {
-			val = set
+// Returns a string result as is, or a list of strings with one per line.
+// Returns false for any other result.
+func rawOutput(result interface{}) (string, bool) {
+	switch v := result.(type) {
+	case string:
+		return v, true
+	case []interface{}:
+		lines := make([]string, len(v))
+		for i, val := range v {
+			str, ok := val.(string)
+			if !ok {
+				return "", false
+			}
+			lines[i] = str
 		}
-		err = c.Set(data, val)
-		if err != nil {
-			quit(err)
+		return strings.Join(lines, "\n"), len(lines) > 0
+	}
+	return "", false
+}
+
+func printResult(result interface{}, indent int, yamlOut bool) {
+	var output []byte
+	var err error
+	if yamlOut {
+		var buf bytes.Buffer
+		enc := yaml.NewEncoder(&buf)
+		if indent != 0 {
+			enc.SetIndent(indent)
 		}
-		result = data
-	} else {
-		result, err = c.Get(data)
+		err = enc.Encode(yamlNumbers(result))
 		if err != nil {
 			quit(err)
 		}
+		fmt.Print(buf.String())
+		return
 	}
-
-	var output []byte
-	if *indent != 0 {
+	if indent != 0 {
 		var prefix string
-		for i := 0; i < *indent; i++ {
+		for i := 0; i < indent; i++ {
 			prefix += " "
 		}
 		output, err = json.MarshalIndent(result, "", prefix)
@@ -0,0 +1,381 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kivera-io/jsonpath"
+)
+
+func TestApplyPatch(t *testing.T) {
+	tests := []struct {
+		name       string
+		data       string
+		patch      string
+		want       string
+		wantErr    bool
+		wantErrMsg string
+	}{
+		{
+			name: "set-and-append",
+			data: `{"key1": {"key2": "val"}, "array": [1]}`,
+			patch: `[
+				{"op": "set", "path": "key1.key2", "value": "new"},
+				{"op": "set", "path": "key3[1]", "value": true},
+				{"op": "append", "path": "array", "value": 2}
+			]`,
+			want: `{"key1": {"key2": "new"}, "key3": [null, true], "array": [1, 2]}`,
+		},
+		{
+			name: "operations-in-order",
+			data: `{}`,
+			patch: `[
+				{"op": "set", "path": "key1", "value": {"key2": "val"}},
+				{"op": "set", "path": "key1.key2", "value": "new"}
+			]`,
+			want: `{"key1": {"key2": "new"}}`,
+		},
+		{
+			name: "unknown-operation",
+			data: `{}`,
+			patch: `[
+				{"op": "set", "path": "key1", "value": "val"},
+				{"op": "remove", "path": "key1"}
+			]`,
+			wantErr:    true,
+			wantErrMsg: "patch operation 1: unknown operation (remove)",
+		},
+		{
+			name: "invalid-path",
+			data: `{}`,
+			patch: `[
+				{"op": "set", "path": "key1[", "value": "val"}
+			]`,
+			wantErr:    true,
+			wantErrMsg: "patch operation 0: invalid_path",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "patch.json")
+			err := os.WriteFile(file, []byte(tt.patch), 0644)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ops, err := readPatch(file)
+			if err != nil {
+				t.Errorf("readPatch() error = %v", err)
+				return
+			}
+
+			var data interface{}
+			unmarshal([]byte(tt.data), &data)
+			err = applyPatch(data, ops)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("applyPatch() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				if !strings.Contains(err.Error(), tt.wantErrMsg) {
+					t.Errorf("applyPatch() errMsg = %v, wantMsg %v", err, tt.wantErrMsg)
+				}
+				return
+			}
+
+			var want interface{}
+			unmarshal([]byte(tt.want), &want)
+			if !reflect.DeepEqual(data, want) {
+				t.Errorf("applyPatch() = %v, want %v", data, want)
+			}
+		})
+	}
+}
+
+func TestProcessLines(t *testing.T) {
+	get := func(data interface{}) (interface{}, error) {
+		return jsonpath.Get(data, "$.key")
+	}
+	tests := []struct {
+		name       string
+		input      string
+		skipErrors bool
+		want       string
+		wantErrOut string
+		wantErr    bool
+		wantErrMsg string
+	}{
+		{
+			name:  "one-result-per-line",
+			input: "{\"key\": 1}\n{\"key\": \"val\"}\n{\"key\": [1, 2]}\n",
+			want:  "1\n\"val\"\n[1,2]\n",
+		},
+		{
+			name:  "blank-lines",
+			input: "{\"key\": 1}\n\n  \n{\"key\": 2}",
+			want:  "1\n2\n",
+		},
+		{
+			name:       "stop-on-error",
+			input:      "{\"key\": 1}\n{\"other\": 2}\n{\"key\": 3}\n",
+			want:       "1\n",
+			wantErr:    true,
+			wantErrMsg: "line 2: not_found",
+		},
+		{
+			name:       "invalid-json",
+			input:      "{\"key\": 1}\n{\"key\":\n",
+			want:       "1\n",
+			wantErr:    true,
+			wantErrMsg: "line 2: unexpected EOF",
+		},
+		{
+			name:       "skip-errors",
+			input:      "{\"key\": 1}\n{bad}\n{\"other\": 2}\n{\"key\": 3}\n",
+			skipErrors: true,
+			want:       "1\n3\n",
+			wantErrOut: "error: line 2: invalid character 'b' looking for beginning of object key string\nerror: line 3: not_found: key does not exist (.key)\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out, errOut bytes.Buffer
+			err := processLines(strings.NewReader(tt.input), &out, &errOut, tt.skipErrors, get)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("processLines() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr && !strings.Contains(err.Error(), tt.wantErrMsg) {
+				t.Errorf("processLines() errMsg = %v, wantMsg %v", err, tt.wantErrMsg)
+			}
+			if out.String() != tt.want {
+				t.Errorf("processLines() output = %q, want %q", out.String(), tt.want)
+			}
+			if errOut.String() != tt.wantErrOut {
+				t.Errorf("processLines() errors = %q, want %q", errOut.String(), tt.wantErrOut)
+			}
+		})
+	}
+}
+
+func TestUnmarshalYAML(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		path    string
+		want    interface{}
+		wantErr bool
+	}{
+		{
+			name: "nested",
+			data: "key1:\n  key2:\n    - val1\n    - key3: val2\n",
+			path: "key1.key2[1].key3",
+			want: "val2",
+		},
+		{
+			name: "non-string-keys",
+			data: "key1:\n  1: val1\n  true: val2\n",
+			path: "key1",
+			want: map[string]interface{}{"1": "val1", "true": "val2"},
+		},
+		{
+			name: "non-string-keys-in-list",
+			data: "- 1: val1\n- 2: val2\n",
+			path: "[*]['2']",
+			want: []interface{}{"val2"},
+		},
+		{
+			name:    "invalid",
+			data:    "key1: [val1\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var data interface{}
+			err := unmarshalYAML([]byte(tt.data), &data)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("unmarshalYAML() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			got, err := jsonpath.Get(data, tt.path)
+			if err != nil {
+				t.Errorf("Get() error = %v", err)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Get() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestYAMLNumbers(t *testing.T) {
+	var data interface{}
+	unmarshal([]byte(`{"int": 9007199254740993, "float": 1.5, "list": [1, "1"]}`), &data)
+	want := map[string]interface{}{
+		"int":   int64(9007199254740993),
+		"float": 1.5,
+		"list":  []interface{}{int64(1), "1"},
+	}
+	if got := yamlNumbers(data); !reflect.DeepEqual(got, want) {
+		t.Errorf("yamlNumbers() = %#v, want %#v", got, want)
+	}
+}
+
+func TestRawOutput(t *testing.T) {
+	tests := []struct {
+		name   string
+		result interface{}
+		want   string
+		wantOk bool
+	}{
+		{name: "string", result: "val \"quoted\"", want: "val \"quoted\"", wantOk: true},
+		{name: "strings", result: []interface{}{"val1", "val2"}, want: "val1\nval2", wantOk: true},
+		{name: "empty-string", result: "", want: "", wantOk: true},
+		{name: "empty-list", result: []interface{}{}, wantOk: false},
+		{name: "mixed-list", result: []interface{}{"val1", 2}, wantOk: false},
+		{name: "number", result: json.Number("1"), wantOk: false},
+		{name: "map", result: map[string]interface{}{"key": "val"}, wantOk: false},
+		{name: "null", result: nil, wantOk: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := rawOutput(tt.result)
+			if ok != tt.wantOk || got != tt.want {
+				t.Errorf("rawOutput() = %q, %v, want %q, %v", got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
+
+func TestGetAll(t *testing.T) {
+	tests := []struct {
+		name       string
+		queries    []string
+		names      []string
+		merge      bool
+		want       string
+		wantErr    bool
+		wantErrMsg string
+	}{
+		{
+			name:    "keyed-by-query",
+			queries: []string{"$.key1", "$.array"},
+			want:    `{"$.key1": "val", "$.array": [1, 2]}`,
+		},
+		{
+			name:    "keyed-by-name",
+			queries: []string{"$.key1", "$.array[0]"},
+			names:   []string{"first", "second"},
+			want:    `{"first": "val", "second": 1}`,
+		},
+		{
+			name:    "merge",
+			queries: []string{"$.key1", "$.array[*]", "$.array"},
+			merge:   true,
+			want:    `["val", 1, 2, [1, 2]]`,
+		},
+		{
+			name:       "missing-path",
+			queries:    []string{"$.key1", "$.missing"},
+			wantErr:    true,
+			wantErrMsg: "$.missing: not_found",
+		},
+		{
+			name:       "names-mismatch",
+			queries:    []string{"$.key1", "$.array"},
+			names:      []string{"first"},
+			wantErr:    true,
+			wantErrMsg: "got 1 names for 2 queries",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var data interface{}
+			json.Unmarshal([]byte(`{"key1": "val", "array": [1, 2]}`), &data)
+			got, err := getAll(data, tt.queries, tt.names, tt.merge)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("getAll() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				if !strings.Contains(err.Error(), tt.wantErrMsg) {
+					t.Errorf("getAll() errMsg = %v, wantMsg %v", err, tt.wantErrMsg)
+				}
+				return
+			}
+			var want interface{}
+			json.Unmarshal([]byte(tt.want), &want)
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("getAll() = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestDeleteValues(t *testing.T) {
+	tests := []struct {
+		name       string
+		data       string
+		query      string
+		want       string
+		wantErr    bool
+		wantErrMsg string
+	}{
+		{
+			name:  "key",
+			data:  `{"key1": "val", "key2": "val"}`,
+			query: "$.key1",
+			want:  `{"key2": "val"}`,
+		},
+		{
+			name:  "nested-index",
+			data:  `{"array": [1, 2, 3]}`,
+			query: "$.array[1]",
+			want:  `{"array": [1, 3]}`,
+		},
+		{
+			name:  "root-array",
+			data:  `[1, 2, 3]`,
+			query: "$[0]",
+			want:  `[2, 3]`,
+		},
+		{
+			name:       "missing-path",
+			data:       `{"key1": "val"}`,
+			query:      "$.missing",
+			wantErr:    true,
+			wantErrMsg: "not_found",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var data interface{}
+			unmarshal([]byte(tt.data), &data)
+			got, err := deleteValues(data, tt.query)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("deleteValues() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				if !strings.Contains(err.Error(), tt.wantErrMsg) {
+					t.Errorf("deleteValues() errMsg = %v, wantMsg %v", err, tt.wantErrMsg)
+				}
+				return
+			}
+			var want interface{}
+			unmarshal([]byte(tt.want), &want)
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("deleteValues() = %v, want %v", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package jsonpath
+
+import (
+	"reflect"
+)
+
+var interfaceType = reflect.TypeOf((*interface{})(nil)).Elem()
+
+// Returns true for segments that select a single key or index, which can be
+// resolved without reflection
+func (s segment) isSimple() bool {
+	if s.isMulti || s.isRecursive || s.isWildcard || s.isLength || s.isSortedKeys || s.filter != nil || s.keyFilter != nil {
+		return false
+	}
+	return (s.isKey && len(s.keys) == 1) || (s.isIndex && len(s.indexes) == 1)
+}
+
+// Resolves a simple segment against the map[string]interface{} and
+// []interface{} values produced by encoding/json. Returns false if the value
+// is another type or the key or index does not exist.
+func lookupJSON(object interface{}, seg segment) (interface{}, bool) {
+	switch v := object.(type) {
+	case map[string]interface{}:
+		if !seg.isKey {
+			return nil, false
+		}
+		next, ok := v[seg.keys[0]]
+		return next, ok
+	case []interface{}:
+		if !seg.isIndex {
+			return nil, false
+		}
+		idx := seg.indexes[0].idx
+		if idx < 0 {
+			idx += len(v)
+		}
+		if idx < 0 || idx >= len(v) {
+			return nil, false
+		}
+		return v[idx], true
+	}
+	return nil, false
+}
+
+// Replaces the value found by lookupJSON
+func replaceJSON(object interface{}, seg segment, value interface{}) {
+	switch v := object.(type) {
+	case map[string]interface{}:
+		v[seg.keys[0]] = value
+	case []interface{}:
+		idx := seg.indexes[0].idx
+		if idx < 0 {
+			idx += len(v)
+		}
+		v[idx] = value
+	}
+}
+
+// Gets values from encoding/json trees without reflection. Anything that
+// cannot be resolved by lookupJSON, including missing paths, is handed to
+// getNestedValues so results and errors match.
+func (c *Compiled) getJSONValues(object interface{}, path []segment) ([]interface{}, *Error) {
+	if c.depth != nil || c.reached != nil || c.parents != nil || c.key != nil || c.walk != nil || c.trace != nil {
+		// depth, reached, parent containers, keys, walks and traces are only tracked by getNestedValues
+		return c.getNestedValues(reflect.ValueOf(object), path)
+	}
+	current := object
+	for i, seg := range path {
+		if !seg.isSimple() {
+			return c.getNestedValues(reflect.ValueOf(current), path[i:])
+		}
+		next, ok := lookupJSON(current, seg)
+		if !ok {
+			return c.getNestedValues(reflect.ValueOf(current), path[i:])
+		}
+		if err := c.visit(); err != nil {
+			return nil, err
+		}
+		current = next
+	}
+	if c.unwrapNullable {
+		return c.getNestedValues(reflect.ValueOf(current), nil)
+	}
+	if err := c.visit(); err != nil {
+		return nil, err
+	}
+	return []interface{}{current}, nil
+}
+
+// Sets values in encoding/json trees without reflection, falling back to
+// setNestedValues in the same way as getJSONValues
+func (c *Compiled) setJSONValues(object reflect.Value, path []segment, value func(reflect.Value) interface{}, valueSet *bool) (reflect.Value, *Error) {
+	if len(path) == 0 || !object.IsValid() || !object.CanInterface() || c.location != nil || c.missing != nil {
+		return c.setNestedValues(object, nil, path, value, valueSet)
+	}
+	// the container holding current, and the segment used to find it
+	var parent interface{}
+	var parentSeg segment
+	current := object.Interface()
+	for i, seg := range path {
+		var next interface{}
+		ok := seg.isSimple()
+		if ok {
+			next, ok = lookupJSON(current, seg)
+		}
+		if !ok {
+			if i == 0 {
+				return c.setNestedValues(object, nil, path, value, valueSet)
+			}
+			temp, err := c.setNestedValues(reflect.ValueOf(&current).Elem(), interfaceType, path[i:], value, valueSet)
+			if err != nil && err.Code != RecursiveMiss {
+				return object, err
+			}
+			if temp.IsValid() {
+				replaceJSON(parent, parentSeg, temp.Interface())
+			}
+			return object, err
+		}
+		parent = current
+		parentSeg = seg
+		current = next
+	}
+	*valueSet = true
+	result := value(reflect.ValueOf(&current).Elem())
+	if result != nil {
+		replaceJSON(parent, parentSeg, result)
+	}
+	return object, nil
+}
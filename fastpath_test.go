@@ -0,0 +1,114 @@
+package jsonpath
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func getDeepData(depth int) (interface{}, string) {
+	var data interface{} = "val"
+	keys := make([]string, depth)
+	for i := depth - 1; i >= 0; i -= 1 {
+		if i%2 == 0 {
+			keys[i] = fmt.Sprintf("key%d", i)
+			data = map[string]interface{}{keys[i]: data, "other": i}
+		} else {
+			keys[i] = "[1]"
+			data = []interface{}{i, data}
+		}
+	}
+	return data, strings.ReplaceAll(strings.Join(keys, "."), ".[", "[")
+}
+
+func TestFastPath(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+	}{
+		{name: "key", path: "key1.key2"},
+		{name: "index", path: "key4[1].key1"},
+		{name: "negative-index", path: "key3.array[-1]"},
+		{name: "null", path: "key5.null_value"},
+		{name: "mixed", path: "key2.array[0].subkey"},
+		{name: "fallback-multi", path: "key3.map.*"},
+		{name: "fallback-missing", path: "key1.missing.key3"},
+		{name: "fallback-index-on-map", path: "key1[0]"},
+		{name: "fallback-key-on-slice", path: "key4.key1"},
+		{name: "fallback-out-of-range", path: "key4[3].key1"},
+	}
+	for _, tt := range tests {
+		if runTest != "" && tt.name != runTest {
+			continue
+		}
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := Compile(tt.path, PreserveOrder())
+			if err != nil {
+				t.Errorf("Compile() error = %v", err)
+				return
+			}
+			got, gotErr := c.getJSONValues(getData(), c.segments)
+			want, wantErr := c.getNestedValues(reflect.ValueOf(getData()), c.segments)
+			if !reflect.DeepEqual(gotErr, wantErr) {
+				t.Errorf("getJSONValues() error = %v, want %v", gotErr, wantErr)
+			}
+			if wantErr == nil && !reflect.DeepEqual(got, want) {
+				t.Errorf("getJSONValues() = %v, want %v", got, want)
+			}
+
+			gotObject, wantObject := getData(), getData()
+			var gotSet, wantSet bool
+			_, gotErr = c.setJSONValues(reflect.ValueOf(gotObject), c.segments, staticValue(newVal), &gotSet)
+			_, wantErr = c.setNestedValues(reflect.ValueOf(wantObject), nil, c.segments, staticValue(newVal), &wantSet)
+			if !reflect.DeepEqual(gotErr, wantErr) || gotSet != wantSet {
+				t.Errorf("setJSONValues() error = %v, want %v", gotErr, wantErr)
+			}
+			if !reflect.DeepEqual(gotObject, wantObject) {
+				t.Errorf("setJSONValues() = %v, want %v", gotObject, wantObject)
+			}
+		})
+	}
+}
+
+func BenchmarkGet(b *testing.B) {
+	data, path := getDeepData(20)
+	c, err := Compile(path)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Run("reflect", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i += 1 {
+			c.getNestedValues(reflect.ValueOf(data), c.segments)
+		}
+	})
+	b.Run("fast-path", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i += 1 {
+			c.getJSONValues(data, c.segments)
+		}
+	})
+}
+
+func BenchmarkSet(b *testing.B) {
+	data, path := getDeepData(20)
+	c, err := Compile(path)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Run("reflect", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i += 1 {
+			var valueSet bool
+			c.setNestedValues(reflect.ValueOf(data), nil, c.segments, staticValue(newVal), &valueSet)
+		}
+	})
+	b.Run("fast-path", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i += 1 {
+			var valueSet bool
+			c.setJSONValues(reflect.ValueOf(data), c.segments, staticValue(newVal), &valueSet)
+		}
+	})
+}
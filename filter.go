@@ -0,0 +1,536 @@
+package jsonpath
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+var keyFilterRegex = regexp.MustCompile(`^\?\(\s*#key\s*=~\s*/(.*)/(i?)\s*\)$`)
+
+var keyPatternRegex = regexp.MustCompile(`^~\s*/(.*)/(i?)$`)
+
+var filterOperators = []string{"==", "!=", "<=", ">=", "<", ">"}
+
+type QueryOptions struct {
+	documents map[string]interface{}
+}
+
+// Binds a document that can be referenced in filters as $name
+func BindDocument(name string, document interface{}) func(*QueryOptions) {
+	return func(q *QueryOptions) {
+		q.documents[name] = document
+	}
+}
+
+type filter struct {
+	raw   string
+	left  filterOperand
+	op    string
+	right *filterOperand
+}
+
+type filterOperand struct {
+	// path relative to the document, nil for literal values
+	path *Compiled
+	// "@" for the current element, "$" for the root, otherwise a bound document
+	document string
+	value    interface{}
+	// arithmetic on other operands, such as @.price * @.qty
+	arithmetic *arithmetic
+}
+
+type arithmetic struct {
+	op    byte
+	left  filterOperand
+	right filterOperand
+}
+
+// Parses filters in the form ?(#key =~ /pattern/) or ?(<operand> [<op> <operand>])
+func parseFilter(result segment, key string) (segment, error) {
+	if !strings.HasPrefix(key, "?(") || !strings.HasSuffix(key, ")") {
+		return result, &Error{Code: InvalidPath, Msg: fmt.Sprintf("invalid filter (%s)", key)}
+	}
+	expr := strings.TrimSpace(key[2 : len(key)-1])
+	if strings.HasPrefix(expr, "#") {
+		return parseKeyFilter(result, key)
+	}
+
+	f := &filter{raw: key}
+	left, op, right := splitFilter(expr)
+	var err error
+	f.left, err = parseFilterOperand(left)
+	if err != nil {
+		return result, err
+	}
+	if op != "" {
+		f.op = op
+		operand, err := parseFilterOperand(right)
+		if err != nil {
+			return result, err
+		}
+		f.right = &operand
+	}
+	result.filter = f
+	result.isMulti = true
+	return result, nil
+}
+
+// Parses key filters in the form ?(#key =~ /pattern/)
+func parseKeyFilter(result segment, filter string) (segment, error) {
+	match := keyFilterRegex.FindStringSubmatch(filter)
+	if len(match) == 0 {
+		return result, &Error{Code: InvalidPath, Msg: fmt.Sprintf("invalid key filter (%s)", filter)}
+	}
+	return withKeyPattern(result, match[1], match[2])
+}
+
+// Parses key patterns in the form ~/pattern/, which match keys in the same
+// way as key filters
+func parseKeyPattern(result segment, key string) (segment, error) {
+	match := keyPatternRegex.FindStringSubmatch(key)
+	if len(match) == 0 {
+		return result, &Error{Code: InvalidPath, Msg: fmt.Sprintf("invalid key pattern (%s)", key)}
+	}
+	return withKeyPattern(result, match[1], match[2])
+}
+
+func withKeyPattern(result segment, pattern string, flags string) (segment, error) {
+	expr := strings.ReplaceAll(pattern, "\\/", "/")
+	if flags != "" {
+		expr = "(?" + flags + ")" + expr
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return result, &Error{Code: InvalidPath, Msg: fmt.Sprintf("invalid key filter pattern (%s)", pattern)}
+	}
+	result.keyFilter = re
+	result.isKey = true
+	result.isMulti = true
+	return result, nil
+}
+
+// Parses glob keys such as user_*, split on each *, which matches any run of
+// characters
+func parseGlobKey(result segment, parts []string) (segment, error) {
+	for i, part := range parts {
+		parts[i] = regexp.QuoteMeta(part)
+	}
+	result.keyFilter = regexp.MustCompile("^" + strings.Join(parts, ".*") + "$")
+	result.isKey = true
+	result.isMulti = true
+	return result, nil
+}
+
+// Splits a filter expression on the first comparison operator found outside
+// of quotes and brackets
+func splitFilter(expr string) (string, string, string) {
+	var inQuote bool
+	var quoteChar byte
+	var depth int
+	for i := 0; i < len(expr); i += 1 {
+		c := expr[i]
+		if inQuote {
+			if c == quoteChar && expr[i-1] != '\\' {
+				inQuote = false
+			}
+			continue
+		}
+		switch c {
+		case '\'', '"':
+			inQuote = true
+			quoteChar = c
+			continue
+		case '[':
+			depth += 1
+			continue
+		case ']':
+			depth -= 1
+			continue
+		}
+		if depth != 0 {
+			continue
+		}
+		for _, op := range filterOperators {
+			if strings.HasPrefix(expr[i:], op) {
+				return expr[:i], op, expr[i+len(op):]
+			}
+		}
+	}
+	return expr, "", ""
+}
+
+func parseFilterOperand(operand string) (filterOperand, error) {
+	result := filterOperand{}
+	operand = strings.TrimSpace(operand)
+	if operand == "" {
+		return result, &Error{Code: InvalidPath, Msg: "empty filter operand"}
+	}
+
+	if i := splitArithmetic(operand); i != -1 {
+		return parseArithmetic(operand, i)
+	}
+
+	switch operand[0] {
+	case '@':
+		result.document = "@"
+		operand = operand[1:]
+	case '$':
+		name := strings.IndexFunc(operand[1:], func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+		})
+		if name == -1 {
+			name = len(operand) - 1
+		}
+		result.document = operand[1 : name+1]
+		if result.document == "" {
+			result.document = "$"
+		}
+		operand = operand[name+1:]
+	}
+
+	if result.document != "" {
+		path, err := Compile("$" + operand)
+		if err != nil {
+			return result, &Error{Code: InvalidPath, Msg: fmt.Sprintf("invalid filter path (%s)", operand)}
+		}
+		result.path = path
+		return result, nil
+	}
+
+	if len(operand) >= 2 && (operand[0] == '\'' || operand[0] == '"') && operand[len(operand)-1] == operand[0] {
+		quote := string(operand[0])
+		result.value = strings.ReplaceAll(operand[1:len(operand)-1], "\\"+quote, quote)
+		return result, nil
+	}
+
+	switch operand {
+	case "true":
+		result.value = true
+	case "false":
+		result.value = false
+	case "null":
+		result.value = nil
+	default:
+		// keep integers exact so they can be compared with large json.Number values
+		if i, err := strconv.ParseInt(operand, 10, 64); err == nil {
+			result.value = i
+			break
+		}
+		num, err := strconv.ParseFloat(operand, 64)
+		if err != nil {
+			return result, &Error{Code: InvalidPath, Msg: fmt.Sprintf("invalid filter operand (%s)", operand)}
+		}
+		result.value = num
+	}
+	return result, nil
+}
+
+// Finds the arithmetic operator to split an operand on, which is the last
+// operator with the lowest precedence so that operators are left associative.
+// Operators must be surrounded by whitespace, so they are not confused with
+// keys, wildcards or negative numbers. Returns -1 if there is no operator.
+func splitArithmetic(expr string) int {
+	add, mul := -1, -1
+	var inQuote bool
+	var quoteChar byte
+	var depth int
+	for i := 0; i < len(expr); i += 1 {
+		c := expr[i]
+		if inQuote {
+			if c == quoteChar && expr[i-1] != '\\' {
+				inQuote = false
+			}
+			continue
+		}
+		switch c {
+		case '\'', '"':
+			inQuote = true
+			quoteChar = c
+		case '[':
+			depth += 1
+		case ']':
+			depth -= 1
+		case '+', '-', '*', '/':
+			if depth == 0 && i > 0 && i < len(expr)-1 && expr[i-1] == ' ' && expr[i+1] == ' ' {
+				if c == '+' || c == '-' {
+					add = i
+				} else {
+					mul = i
+				}
+			}
+		}
+	}
+	if add != -1 {
+		return add
+	}
+	return mul
+}
+
+// Parses an arithmetic operand split on the operator at index i. Operands must
+// be numbers, paths relative to the current element or further arithmetic.
+func parseArithmetic(expr string, i int) (filterOperand, error) {
+	result := filterOperand{arithmetic: &arithmetic{op: expr[i]}}
+	parts := []string{expr[:i], expr[i+1:]}
+	operands := []*filterOperand{&result.arithmetic.left, &result.arithmetic.right}
+	for j, part := range parts {
+		operand, err := parseFilterOperand(part)
+		if err != nil {
+			return result, err
+		}
+		if !operand.isNumeric() {
+			return result, &Error{Code: InvalidPath, Msg: fmt.Sprintf("arithmetic operands must be numbers or @ paths (%s)", strings.TrimSpace(part))}
+		}
+		*operands[j] = operand
+	}
+	return result, nil
+}
+
+// Returns true if the operand can be used in arithmetic
+func (o filterOperand) isNumeric() bool {
+	if o.arithmetic != nil {
+		return true
+	}
+	if o.path != nil {
+		return o.document == "@"
+	}
+	_, ok := toFloat(o.value)
+	return ok
+}
+
+// Applies the options of the parent path to the paths used within the filter
+func (f *filter) inherit(c *Compiled) {
+	f.left.inherit(c)
+	if f.right != nil {
+		f.right.inherit(c)
+	}
+}
+
+func (o *filterOperand) inherit(c *Compiled) {
+	if o.arithmetic != nil {
+		o.arithmetic.left.inherit(c)
+		o.arithmetic.right.inherit(c)
+	}
+	if o.path == nil {
+		return
+	}
+	o.path.structTag = c.structTag
+	o.path.structTagSet = c.structTagSet
+	o.path.unwrapNullable = c.unwrapNullable
+}
+
+// Returns a copy of the compiled path that holds the documents for a single query
+func (c *Compiled) withQuery(object interface{}, options ...func(*QueryOptions)) *Compiled {
+	query := &QueryOptions{documents: map[string]interface{}{}}
+	for _, option := range options {
+		option(query)
+	}
+	query.documents["$"] = object
+	if c.rootName != "" {
+		query.documents[c.rootName] = object
+	}
+	withQuery := *c
+	withQuery.query = query
+	return &withQuery
+}
+
+func (c *Compiled) matchesFilter(f *filter, current reflect.Value) bool {
+	left, ok := c.filterValue(f.left, current)
+	if !ok {
+		return false
+	}
+	if f.right == nil {
+		return true
+	}
+	right, ok := c.filterValue(*f.right, current)
+	if !ok {
+		return false
+	}
+	return compareValues(left, f.op, right)
+}
+
+func (c *Compiled) filterValue(operand filterOperand, current reflect.Value) (interface{}, bool) {
+	if operand.arithmetic != nil {
+		return c.arithmeticValue(*operand.arithmetic, current)
+	}
+	if operand.path == nil {
+		return operand.value, true
+	}
+	var document interface{}
+	if operand.document == "@" {
+		if current.IsValid() && current.CanInterface() {
+			document = current.Interface()
+		}
+	} else {
+		var ok bool
+		if c.query == nil {
+			return nil, false
+		}
+		document, ok = c.query.documents[operand.document]
+		if !ok {
+			return nil, false
+		}
+	}
+	path := operand.path
+	if path.hasFilter {
+		withQuery := *path
+		withQuery.query = c.query
+		path = &withQuery
+	}
+	value, err := path.Get(document)
+	if err != nil {
+		return nil, false
+	}
+	return value, true
+}
+
+// Evaluates arithmetic on numeric operands. Integers are kept exact, except
+// when dividing. Operands that are not numbers and division by zero have no
+// value, so the filter does not match.
+func (c *Compiled) arithmeticValue(a arithmetic, current reflect.Value) (interface{}, bool) {
+	left, ok := c.filterValue(a.left, current)
+	if !ok {
+		return nil, false
+	}
+	right, ok := c.filterValue(a.right, current)
+	if !ok {
+		return nil, false
+	}
+
+	leftInt, leftOk := toInt(left)
+	rightInt, rightOk := toInt(right)
+	if leftOk && rightOk && a.op != '/' {
+		switch a.op {
+		case '+':
+			return leftInt + rightInt, true
+		case '-':
+			return leftInt - rightInt, true
+		case '*':
+			return leftInt * rightInt, true
+		}
+	}
+
+	leftNum, leftOk := toFloat(left)
+	rightNum, rightOk := toFloat(right)
+	if !leftOk || !rightOk {
+		return nil, false
+	}
+	switch a.op {
+	case '+':
+		return leftNum + rightNum, true
+	case '-':
+		return leftNum - rightNum, true
+	case '*':
+		return leftNum * rightNum, true
+	case '/':
+		if rightNum == 0 {
+			return nil, false
+		}
+		return leftNum / rightNum, true
+	}
+	return nil, false
+}
+
+func compareValues(left interface{}, op string, right interface{}) bool {
+	leftInt, leftOk := toInt(left)
+	rightInt, rightOk := toInt(right)
+	if leftOk && rightOk {
+		switch op {
+		case "==":
+			return leftInt == rightInt
+		case "!=":
+			return leftInt != rightInt
+		case "<":
+			return leftInt < rightInt
+		case "<=":
+			return leftInt <= rightInt
+		case ">":
+			return leftInt > rightInt
+		case ">=":
+			return leftInt >= rightInt
+		}
+		return false
+	}
+
+	leftNum, leftOk := toFloat(left)
+	rightNum, rightOk := toFloat(right)
+	if leftOk && rightOk {
+		switch op {
+		case "==":
+			return leftNum == rightNum
+		case "!=":
+			return leftNum != rightNum
+		case "<":
+			return leftNum < rightNum
+		case "<=":
+			return leftNum <= rightNum
+		case ">":
+			return leftNum > rightNum
+		case ">=":
+			return leftNum >= rightNum
+		}
+		return false
+	}
+
+	leftStr, leftOk := left.(string)
+	rightStr, rightOk := right.(string)
+	if leftOk && rightOk {
+		switch op {
+		case "==":
+			return leftStr == rightStr
+		case "!=":
+			return leftStr != rightStr
+		case "<":
+			return leftStr < rightStr
+		case "<=":
+			return leftStr <= rightStr
+		case ">":
+			return leftStr > rightStr
+		case ">=":
+			return leftStr >= rightStr
+		}
+		return false
+	}
+
+	switch op {
+	case "==":
+		return reflect.DeepEqual(left, right)
+	case "!=":
+		return !reflect.DeepEqual(left, right)
+	}
+	return false
+}
+
+// Returns the value of signed integers and integral json.Numbers
+func toInt(value interface{}) (int64, bool) {
+	if num, ok := value.(json.Number); ok {
+		i, err := num.Int64()
+		return i, err == nil
+	}
+	v := reflect.ValueOf(value)
+	if v.CanInt() {
+		return v.Int(), true
+	}
+	return 0, false
+}
+
+func toFloat(value interface{}) (float64, bool) {
+	if num, ok := value.(json.Number); ok {
+		f, err := num.Float64()
+		return f, err == nil
+	}
+	v := reflect.ValueOf(value)
+	switch {
+	case v.CanInt():
+		return float64(v.Int()), true
+	case v.CanUint():
+		return float64(v.Uint()), true
+	case v.CanFloat():
+		return v.Float(), true
+	}
+	return 0, false
+}
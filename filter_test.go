@@ -0,0 +1,396 @@
+package jsonpath
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var users = `
+{
+	"users": [
+		{
+			"id": 1,
+			"name": "alice",
+			"team": "a"
+		},
+		{
+			"id": 2,
+			"name": "bob",
+			"team": "b"
+		},
+		{
+			"id": 3,
+			"name": "carol"
+		}
+	],
+	"owner": 2
+}`
+
+var teams = `
+{
+	"teams": [
+		{
+			"id": "a",
+			"owner": 1
+		},
+		{
+			"id": "b",
+			"owner": 3
+		}
+	],
+	"default": {
+		"id": "b"
+	}
+}`
+
+var orders = `
+{
+	"orders": [
+		{
+			"id": 1,
+			"price": 20,
+			"qty": 10,
+			"discount": 5
+		},
+		{
+			"id": 2,
+			"price": 12.5,
+			"qty": 4,
+			"discount": 0
+		},
+		{
+			"id": 3,
+			"price": "free",
+			"qty": 1,
+			"discount": 0
+		},
+		{
+			"id": 4,
+			"price": 30,
+			"qty": 2
+		}
+	]
+}`
+
+func unmarshal(data string) interface{} {
+	var result interface{}
+	err := json.Unmarshal([]byte(data), &result)
+	if err != nil {
+		panic(err)
+	}
+	return result
+}
+
+func TestFilter(t *testing.T) {
+	type args struct {
+		object    interface{}
+		path      string
+		documents map[string]interface{}
+		bind      string
+	}
+
+	tests := map[string][]struct {
+		name        string
+		args        args
+		want        interface{}
+		wantErr     bool
+		wantErrCode string
+		wantErrMsg  string
+	}{
+		"filter": {
+			{
+				name: "equals-number",
+				args: args{
+					object: unmarshal(users),
+					path:   "users[?(@.id == 2)].name",
+				},
+				want: []interface{}{"bob"},
+			},
+			{
+				name: "equals-string",
+				args: args{
+					object: unmarshal(users),
+					path:   "users[?(@['name'] == 'carol')].id",
+				},
+				want: []interface{}{float64(3)},
+			},
+			{
+				name: "comparison",
+				args: args{
+					object: unmarshal(users),
+					path:   "users[?(@.id >= 2)].name",
+				},
+				want: []interface{}{"bob", "carol"},
+			},
+			{
+				name: "not-equals",
+				args: args{
+					object: unmarshal(users),
+					path:   "users[?(@.name != \"alice\")].id",
+				},
+				want: []interface{}{float64(2), float64(3)},
+			},
+			{
+				name: "exists",
+				args: args{
+					object: unmarshal(users),
+					path:   "users[?(@.team)].name",
+				},
+				want: []interface{}{"alice", "bob"},
+			},
+			{
+				name: "root",
+				args: args{
+					object: unmarshal(users),
+					path:   "users[?(@.id == $.owner)].name",
+				},
+				want: []interface{}{"bob"},
+			},
+			{
+				name: "map-values",
+				args: args{
+					object: getData(),
+					path:   "key3.map[?(@ == 'val2')]",
+				},
+				want: []interface{}{"val2"},
+			},
+			{
+				name: "recursive",
+				args: args{
+					object: getData(),
+					path:   "key6..[?(@.recursive == 'val4')]",
+				},
+				want: []interface{}{map[string]interface{}{"recursive": "val4"}},
+			},
+			{
+				name: "no-match",
+				args: args{
+					object: unmarshal(users),
+					path:   "users[?(@.id > 10)]",
+				},
+				want: []interface{}{},
+			},
+		},
+		"bind": {
+			{
+				name: "bound-document",
+				args: args{
+					object:    unmarshal(users),
+					path:      "users[?(@.id == $teams.teams[0].owner)].name",
+					documents: map[string]interface{}{"teams": unmarshal(teams)},
+				},
+				want: []interface{}{"alice"},
+			},
+			{
+				name: "join",
+				args: args{
+					object:    unmarshal(users),
+					path:      "users[?(@.team == $other.default.id)].name",
+					documents: map[string]interface{}{"other": unmarshal(teams)},
+				},
+				want: []interface{}{"bob"},
+			},
+			{
+				name: "bound-root",
+				args: args{
+					object: unmarshal(users),
+					path:   "users[?(@.id == $doc.owner)].name",
+					bind:   "doc",
+				},
+				want: []interface{}{"bob"},
+			},
+			{
+				name: "missing-document",
+				args: args{
+					object: unmarshal(users),
+					path:   "users[?(@.id == $other.owner)].name",
+				},
+				want: []interface{}{},
+			},
+		},
+		"arithmetic": {
+			{
+				name: "multiply",
+				args: args{
+					object: unmarshal(orders),
+					path:   "orders[?(@.price * @.qty > 100)].id",
+				},
+				want: []interface{}{float64(1)},
+			},
+			{
+				name: "precedence",
+				args: args{
+					object: unmarshal(orders),
+					path:   "orders[?(@.price * @.qty - @.discount * 2 == 190)].id",
+				},
+				want: []interface{}{float64(1)},
+			},
+			{
+				name: "left-associative",
+				args: args{
+					object: unmarshal(orders),
+					path:   "orders[?(@.qty - 2 - 1 == 1)].id",
+				},
+				want: []interface{}{float64(2)},
+			},
+			{
+				name: "both-sides",
+				args: args{
+					object: unmarshal(orders),
+					path:   "orders[?(@.price / 4 == @.qty - 5)].id",
+				},
+				want: []interface{}{float64(1)},
+			},
+			{
+				name: "negative-literal",
+				args: args{
+					object: unmarshal(orders),
+					path:   "orders[?(@.discount * -1 < 0)].id",
+				},
+				want: []interface{}{float64(1)},
+			},
+			{
+				name: "division-by-zero",
+				args: args{
+					object: unmarshal(orders),
+					path:   "orders[?(@.price / @.discount >= 0)].id",
+				},
+				want: []interface{}{float64(1)},
+			},
+			{
+				name: "non-numeric-and-missing",
+				args: args{
+					object: unmarshal(orders),
+					path:   "orders[?(@.price + @.discount > 0)].id",
+				},
+				want: []interface{}{float64(1), float64(2)},
+			},
+			{
+				name: "integers",
+				args: args{
+					object: []interface{}{map[string]interface{}{"a": 9007199254740993, "b": 2}},
+					path:   "[?(@.a + @.b == 9007199254740995)].a",
+				},
+				want: []interface{}{9007199254740993},
+			},
+			{
+				name: "key-with-dash",
+				args: args{
+					object: []interface{}{map[string]interface{}{"unit-price": 3}},
+					path:   "[?(@.unit-price * 2 == 6)].unit-price",
+				},
+				want: []interface{}{3},
+			},
+			{
+				name: "string-literal",
+				args: args{
+					object: unmarshal(orders),
+					path:   "orders[?(@.price * 'two' > 0)]",
+				},
+				wantErr:     true,
+				wantErrCode: InvalidPath,
+				wantErrMsg:  "arithmetic operands must be numbers or @ paths ('two')",
+			},
+			{
+				name: "root-path",
+				args: args{
+					object: unmarshal(orders),
+					path:   "orders[?(@.price * $.rate > 0)]",
+				},
+				wantErr:     true,
+				wantErrCode: InvalidPath,
+				wantErrMsg:  "arithmetic operands must be numbers or @ paths ($.rate)",
+			},
+		},
+		"errors": {
+			{
+				name: "invalid-operand",
+				args: args{
+					object: unmarshal(users),
+					path:   "users[?(@.id == abc)]",
+				},
+				wantErr:     true,
+				wantErrCode: InvalidPath,
+				wantErrMsg:  "invalid filter operand (abc)",
+			},
+			{
+				name: "invalid-path",
+				args: args{
+					object: unmarshal(users),
+					path:   "users[?(@.id. == 1)]",
+				},
+				wantErr:     true,
+				wantErrCode: InvalidPath,
+				wantErrMsg:  "invalid filter path",
+			},
+			{
+				name: "empty-operand",
+				args: args{
+					object: unmarshal(users),
+					path:   "users[?(@.id == )]",
+				},
+				wantErr:     true,
+				wantErrCode: InvalidPath,
+				wantErrMsg:  "empty filter operand",
+			},
+		},
+	}
+
+	for groupName, group := range tests {
+		for _, tt := range group {
+			testName := fmt.Sprintf("%s-%s", groupName, tt.name)
+			if runTest != "" && testName != runTest {
+				continue
+			}
+			t.Run(testName, func(t *testing.T) {
+				c, err := Compile(tt.args.path)
+				if err == nil {
+					if tt.args.bind != "" {
+						c.Bind(tt.args.bind)
+					}
+					options := []func(*QueryOptions){}
+					for name, doc := range tt.args.documents {
+						options = append(options, BindDocument(name, doc))
+					}
+					var got interface{}
+					got, err = c.GetWith(tt.args.object, options...)
+					if err == nil && !reflect.DeepEqual(got, tt.want) {
+						t.Errorf("GetWith() = %v, want %v", got, tt.want)
+					}
+				}
+				if (err != nil) != tt.wantErr {
+					t.Errorf("GetWith() error = %v, wantErr %v", err, tt.wantErr)
+					return
+				}
+				if tt.wantErr {
+					if err.(*Error).Code != tt.wantErrCode {
+						t.Errorf("GetWith() errCode = %v, wantCode %v", err.(*Error).Code, tt.wantErrCode)
+					}
+					if !strings.Contains(err.Error(), tt.wantErrMsg) {
+						t.Errorf("GetWith() errMsg = %v, wantMsg %v", err.(*Error).Msg, tt.wantErrMsg)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestFilterSet(t *testing.T) {
+	object := unmarshal(users)
+	err := Set(object, "users[?(@.id == $.owner)].admin", true)
+	if err != nil {
+		t.Errorf("Set() error = %v", err)
+		return
+	}
+	got, err := Get(object, "users[?(@.admin == true)].id")
+	if err != nil {
+		t.Errorf("Get() error = %v", err)
+		return
+	}
+	if !reflect.DeepEqual(got, []interface{}{float64(2)}) {
+		t.Errorf("Get() = %v, want %v", got, []interface{}{float64(2)})
+	}
+}
@@ -1,6 +1,10 @@
 package jsonpath
 
 import (
+	"context"
+	"encoding"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"reflect"
 	"regexp"
@@ -8,142 +12,2149 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
 )
 
 var rangeRegex = regexp.MustCompile(`^(-?\d+)?:(-?\d+)?$`)
 
+var stringType = reflect.TypeOf("")
+
+var numberType = reflect.TypeOf(json.Number(""))
+
+var (
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+)
+
 type Compiled struct {
-	raw      string
-	segments []segment
-	hasMulti bool
+	raw       string
+	segments  []segment
+	hasMulti  bool
+	hasFilter bool
+	// branches of a union path, each evaluated against the whole document
+	union []*Compiled
+	// documents available to filters for a single query
+	query *QueryOptions
+	// name that the queried document can be referenced by in filters
+	rootName string
+
+	// only allow setting values on existing paths
+	strictPaths bool
+	// create missing containers, but only allow setting values on existing keys
+	// and indexes
+	updateOnly bool
+	// query struct based off a tag instead of field names
+	structTag    string
+	structTagSet bool
+	// convert struct results into maps
+	structsAsMaps bool
+	// treat sql.Null* style wrappers as their inner value
+	unwrapNullable bool
+	// ignore whitespace around keys outside of brackets
+	tolerantWhitespace bool
+	// traverse map keys in sorted order
+	sortKeys bool
+	// stop recursive descent into nodes that match the recursive segment
+	shallowestRecursive bool
+	// remove duplicate values from multi-match results
+	dedupe bool
+	// sort the results of multi-match queries from Get
+	sortResults bool
+	// maximum number of nodes a single query can visit, 0 for no limit
+	maxVisits int
+	// nodes visited by the current query
+	visits *int
+	// maximum depth of recursive descent, 0 for defaultMaxDepth
+	maxDepth int
+	// depth of recursive descent in the current query
+	nesting *nesting
+	// maximum number of elements setting a value can add to a slice, 0 for
+	// defaultMaxGrowth
+	maxGrowth int
+	// append to arrays when setting a single out of range index, instead of
+	// filling them up to the index
+	appendOnGrow bool
+	// containers of the node being visited, tracked for parent selectors
+	parents *[]reflect.Value
+	// depth of the node being visited, tracked for GetByDepth
+	depth *int
+	// set once the container of the last segment is found, tracked for GetE
+	reached *bool
+	// key of the node being visited, tracked for Keys
+	key *string
+	// callback for each matched value, used by Walk instead of collecting them
+	walk *walker
+	// context of the query, checked while visiting nodes by GetContext
+	cancel *canceller
+	// set when the node being visited did not exist, tracked for SetIfAbsent
+	missing *bool
+	// treat null values as absent in SetIfAbsent
+	nullAsAbsent bool
+	// errors from setting each matched element, collected for SetAll
+	setErrs *[]error
+	// path to the node being visited, tracked for SetAll
+	location *[]string
+	// paths of matched and missing nodes, tracked for GetTrace
+	trace *Trace
+	// require keys within brackets to be quoted
+	strictQuotes bool
+	// return single results as a slice, wrapping values that are not slices
+	asSlice bool
+	// return the value when a path that can match multiple values matches one
+	collapseSingle bool
+	// return the matched values as a slice, even for paths matching one value
+	alwaysSlice bool
+	// get values from slices of [key, value] pairs by key
+	pairsAsMap bool
+	// prefix that refers to the root of the document, instead of $
+	rootToken    string
+	rootTokenSet bool
+	// drop out of range indexes when getting values instead of failing the path
+	lenientIndices bool
+	// skip nil values part way through a path instead of stopping with an error
+	skipNilIntermediates bool
+	// prevent setting values from creating new slices or maps
+	noCreateSlices bool
+	noCreateMaps   bool
+	// set recursive paths that match nothing as if they were not recursive
+	createOnRecursiveMiss bool
+}
+
+type segment struct {
+	raw         string
+	keys        []string
+	keysRefl    []reflect.Value
+	indexes     []index
+	keyFilter   *regexp.Regexp
+	filter      *filter
+	isKey       bool
+	isIndex     bool
+	isWildcard  bool
+	isRecursive bool
+	isMulti     bool
+	isLength    bool
+	// indexes select from the sorted keys of a map
+	isSortedKeys bool
+	// climbs to the container of the current node
+	isParent bool
+	// a function ending the path, length or count
+	function string
+}
+
+type index struct {
+	idx      int
+	start    int
+	hasStart bool
+	end      int
+	hasEnd   bool
+}
+
+type Error struct {
+	Code string
+	Msg  string
+	// The zero-based index of the path segment where traversing an object
+	// failed, and the path it belongs to in normalized form. Path is empty for
+	// errors that are not tied to a segment, such as an invalid path.
+	Segment int
+	Path    string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %s", e.Code, e.Msg)
+}
+
+// Matches errors with the same code, so errors.Is(err, ErrNotFound) is true
+// for any not found error
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// Converts a panic while traversing an object into an InternalError, so that
+// unexpected values cannot crash the caller. Panics from callbacks are held by
+// a callbackPanic instead, and passed on to the caller.
+func recoverError(err *error) {
+	if r := recover(); r != nil {
+		*err = &Error{Code: InternalError, Msg: fmt.Sprintf("%v", r)}
+	}
+}
+
+// Holds a panic from a callback, such as the fn passed to Walk or SetFunc, so
+// it is not converted into an error by recoverError. The panic is raised again
+// by repanic once the traversal has returned.
+type callbackPanic struct {
+	value    interface{}
+	panicked bool
+}
+
+// Calls fn, unless an earlier call panicked
+func (p *callbackPanic) call(fn func()) {
+	if p.panicked {
+		return
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			p.value = r
+			p.panicked = true
+		}
+	}()
+	fn()
+}
+
+func (p *callbackPanic) repanic() {
+	if p.panicked {
+		panic(p.value)
+	}
+}
+
+// Records the segment at the start of path on errors that do not have one.
+// The error is copied, so the segment of the innermost failure is kept as it
+// is returned through each level of the traversal.
+func (c *Compiled) atSegment(err *Error, path []segment) *Error {
+	if err == nil || err.Path != "" || err.Code == RecursiveMiss {
+		return err
+	}
+	result := *err
+	result.Segment = len(c.segments) - len(path)
+	result.Path = c.String()
+	return &result
+}
+
+// Returns true if the path does not match the data, either because it does not
+// exist or because it does not fit the type of the data, e.g. a key on an array
+func notFound(err error) bool {
+	e, ok := err.(*Error)
+	return ok && (e.Code == NotFound || e.Code == TypeMismatch)
+}
+
+const (
+	NotFound      = "not_found"
+	InvalidPath   = "invalid_path"
+	RecursiveMiss = "recursive_miss"
+	LimitExceeded = "limit_exceeded"
+	DepthExceeded = "depth_exceeded"
+	TypeMismatch  = "type_mismatch"
+	InternalError = "internal_error"
+)
+
+// Errors to compare against with errors.Is, which matches any error with the
+// same code
+var (
+	ErrNotFound      = &Error{Code: NotFound, Msg: "path not found"}
+	ErrInvalidPath   = &Error{Code: InvalidPath, Msg: "invalid path"}
+	ErrLimitExceeded = &Error{Code: LimitExceeded, Msg: "limit exceeded"}
+	ErrDepthExceeded = &Error{Code: DepthExceeded, Msg: "depth exceeded"}
+	ErrTypeMismatch  = &Error{Code: TypeMismatch, Msg: "type mismatch"}
+	ErrInternal      = &Error{Code: InternalError, Msg: "internal error"}
+)
+
+// Maximum depth of recursive descent unless set with MaxDepth. Cyclic
+// structures, such as a struct that points to itself, exceed any limit.
+const defaultMaxDepth = 10000
+
+// stops the remaining traversal once a Walk callback returns an error
+const walkStopped = "walk_stopped"
+
+// stops the remaining traversal once the context of GetContext is done
+const canceled = "canceled"
+
+// Number of nodes visited between checks of the context passed to GetContext
+const contextCheckInterval = 64
+
+// Returned by a Walk or WalkPaths callback to skip recursive descent into the
+// value passed to it
+var SkipRecursion = errors.New("skip recursion")
+
+const defaultMaxGrowth = 100000
+
+func (c *Compiled) RawPath() string {
+	return c.raw
+}
+
+// Returns the prefix that refers to the root of the document
+func (c *Compiled) root() string {
+	if c.rootTokenSet {
+		return c.rootToken
+	}
+	return "$"
+}
+
+// Returns the number of segments in the path. The segments of every branch
+// are counted for union paths.
+func (c *Compiled) SegmentCount() int {
+	count := len(c.segments)
+	for _, b := range c.union {
+		count += b.SegmentCount()
+	}
+	return count
+}
+
+// Returns true if any segment is a wildcard, including recursive wildcards
+func (c *Compiled) HasWildcard() bool {
+	return c.anySegment(func(s segment) bool { return s.isWildcard })
+}
+
+// Returns true if any segment uses recursive descent
+func (c *Compiled) HasRecursive() bool {
+	return c.anySegment(func(s segment) bool { return s.isRecursive })
+}
+
+// Returns true if the path can match multiple values, in which case Get
+// returns a slice of the matched values
+func (c *Compiled) IsMulti() bool {
+	return c.hasMulti
+}
+
+func (c *Compiled) anySegment(fn func(segment) bool) bool {
+	for _, b := range c.branches() {
+		if slices.ContainsFunc(b.segments, fn) {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the path in bracket notation, so equivalent paths such as
+// "key1[key2]" and "$.key1.['key2']" render the same
+func (c *Compiled) String() string {
+	if c.union != nil {
+		branches := make([]string, len(c.union))
+		for i, b := range c.union {
+			branches[i] = b.String()
+		}
+		return strings.Join(branches, " | ")
+	}
+	var b strings.Builder
+	b.WriteString(c.root())
+	for _, seg := range c.segments {
+		b.WriteString(seg.String())
+	}
+	return b.String()
+}
+
+// Returns true if both paths have the same segments once normalized, so
+// $.a.b and a['b'] are equal, however they were written. The root token is
+// not compared.
+func (c *Compiled) Equal(other *Compiled) bool {
+	if c == nil || other == nil {
+		return c == other
+	}
+	if len(c.union) != len(other.union) || len(c.segments) != len(other.segments) {
+		return false
+	}
+	for i := range c.union {
+		if !c.union[i].Equal(other.union[i]) {
+			return false
+		}
+	}
+	for i := range c.segments {
+		if c.segments[i].String() != other.segments[i].String() {
+			return false
+		}
+	}
+	return true
+}
+
+func (s segment) String() string {
+	var prefix string
+	if s.isRecursive {
+		prefix = ".."
+	}
+	switch {
+	case s.filter != nil || s.keyFilter != nil:
+		raw := strings.TrimLeft(s.raw, ".")
+		if !s.isRecursive && !strings.HasPrefix(raw, "[") {
+			// glob keys are written in dot notation
+			prefix = "."
+		}
+		return prefix + raw
+	case s.isWildcard:
+		return prefix + "[*]"
+	case s.function != "":
+		return "." + s.function + "()"
+	case s.isLength:
+		return "[#]"
+	case s.isParent:
+		return "^"
+	}
+	parts := make([]string, 0, len(s.keys)+len(s.indexes))
+	if s.isKey {
+		for _, k := range s.keys {
+			parts = append(parts, quoteKey(k))
+		}
+	}
+	if s.isIndex {
+		for _, idx := range s.indexes {
+			parts = append(parts, idx.String())
+		}
+	}
+	if s.isSortedKeys {
+		prefix = ".sortedKeys()"
+	}
+	return prefix + "[" + strings.Join(parts, ",") + "]"
+}
+
+// Returns the key as a bracket notation path segment, such as ['key'], with
+// quotes and backslashes escaped. Segments can be appended to a path, or to
+// each other, to build a path that matches the key literally.
+func QuoteKey(key string) string {
+	return "[" + quoteKey(key) + "]"
+}
+
+func quoteKey(key string) string {
+	key = strings.ReplaceAll(key, "\\", "\\\\")
+	return "'" + strings.ReplaceAll(key, "'", "\\'") + "'"
+}
+
+func (i index) String() string {
+	if !i.hasStart && !i.hasEnd {
+		return strconv.Itoa(i.idx)
+	}
+	var start, end string
+	if i.hasStart {
+		start = strconv.Itoa(i.start)
+	}
+	if i.hasEnd {
+		end = strconv.Itoa(i.end)
+	}
+	return start + ":" + end
+}
+
+func (c *Compiled) EnableStrictPaths() {
+	c.strictPaths = true
+}
+
+func (c *Compiled) UpdateOnly() {
+	c.updateOnly = true
+}
+
+func (c *Compiled) UseStructTag(tag string) {
+	c.structTag = tag
+	c.structTagSet = true
+}
+
+func (c *Compiled) StructsAsMaps() {
+	c.structsAsMaps = true
+}
+
+func (c *Compiled) UnwrapNullable() {
+	c.unwrapNullable = true
+}
+
+func (c *Compiled) TolerantWhitespace() {
+	c.tolerantWhitespace = true
+}
+
+func (c *Compiled) PreserveOrder() {
+	c.sortKeys = true
+}
+
+func (c *Compiled) ShallowestRecursive() {
+	c.shallowestRecursive = true
+}
+
+func (c *Compiled) Dedupe() {
+	c.dedupe = true
+}
+
+func (c *Compiled) SortResults() {
+	c.sortResults = true
+}
+
+func (c *Compiled) MaxVisits(n int) {
+	c.maxVisits = n
+}
+
+func (c *Compiled) MaxDepth(n int) {
+	c.maxDepth = n
+}
+
+func (c *Compiled) MaxGrowth(n int) {
+	c.maxGrowth = n
+}
+
+func (c *Compiled) AppendOnGrow() {
+	c.appendOnGrow = true
+}
+
+func (c *Compiled) StrictQuotes() {
+	c.strictQuotes = true
+}
+
+func (c *Compiled) AsSlice() {
+	c.asSlice = true
+}
+
+func (c *Compiled) CollapseSingle() {
+	c.collapseSingle = true
+}
+
+func (c *Compiled) AlwaysSlice() {
+	c.alwaysSlice = true
+}
+
+func (c *Compiled) PairsAsMap() {
+	c.pairsAsMap = true
+}
+
+func (c *Compiled) RootToken(token string) {
+	c.rootToken = token
+	c.rootTokenSet = true
+}
+
+func (c *Compiled) NullAsAbsent() {
+	c.nullAsAbsent = true
+}
+
+func (c *Compiled) LenientIndices() {
+	c.lenientIndices = true
+}
+
+func (c *Compiled) SkipNilIntermediates() {
+	c.skipNilIntermediates = true
+}
+
+func (c *Compiled) NoCreateSlices() {
+	c.noCreateSlices = true
+}
+
+func (c *Compiled) NoCreateMaps() {
+	c.noCreateMaps = true
+}
+
+func (c *Compiled) CreateOnRecursiveMiss() {
+	c.createOnRecursiveMiss = true
+}
+
+func EnableStrictPaths() func(c *Compiled) {
+	return func(c *Compiled) {
+		c.EnableStrictPaths()
+	}
+}
+
+func UpdateOnly() func(c *Compiled) {
+	return func(c *Compiled) {
+		c.UpdateOnly()
+	}
+}
+
+func UseStructTag(tag string) func(c *Compiled) {
+	return func(c *Compiled) {
+		c.UseStructTag(tag)
+	}
+}
+
+func StructsAsMaps() func(c *Compiled) {
+	return func(c *Compiled) {
+		c.StructsAsMaps()
+	}
+}
+
+func UnwrapNullable() func(c *Compiled) {
+	return func(c *Compiled) {
+		c.UnwrapNullable()
+	}
+}
+
+func TolerantWhitespace() func(c *Compiled) {
+	return func(c *Compiled) {
+		c.TolerantWhitespace()
+	}
+}
+
+func PreserveOrder() func(c *Compiled) {
+	return func(c *Compiled) {
+		c.PreserveOrder()
+	}
+}
+
+func ShallowestRecursive() func(c *Compiled) {
+	return func(c *Compiled) {
+		c.ShallowestRecursive()
+	}
+}
+
+func Dedupe() func(c *Compiled) {
+	return func(c *Compiled) {
+		c.Dedupe()
+	}
+}
+
+func SortResults() func(c *Compiled) {
+	return func(c *Compiled) {
+		c.SortResults()
+	}
+}
+
+func MaxVisits(n int) func(c *Compiled) {
+	return func(c *Compiled) {
+		c.MaxVisits(n)
+	}
+}
+
+func MaxDepth(n int) func(c *Compiled) {
+	return func(c *Compiled) {
+		c.MaxDepth(n)
+	}
+}
+
+func MaxGrowth(n int) func(c *Compiled) {
+	return func(c *Compiled) {
+		c.MaxGrowth(n)
+	}
+}
+
+func AppendOnGrow() func(c *Compiled) {
+	return func(c *Compiled) {
+		c.AppendOnGrow()
+	}
+}
+
+func StrictQuotes() func(c *Compiled) {
+	return func(c *Compiled) {
+		c.StrictQuotes()
+	}
+}
+
+func AsSlice() func(c *Compiled) {
+	return func(c *Compiled) {
+		c.AsSlice()
+	}
+}
+
+func CollapseSingle() func(c *Compiled) {
+	return func(c *Compiled) {
+		c.CollapseSingle()
+	}
+}
+
+func AlwaysSlice() func(c *Compiled) {
+	return func(c *Compiled) {
+		c.AlwaysSlice()
+	}
+}
+
+func PairsAsMap() func(c *Compiled) {
+	return func(c *Compiled) {
+		c.PairsAsMap()
+	}
+}
+
+func RootToken(token string) func(c *Compiled) {
+	return func(c *Compiled) {
+		c.RootToken(token)
+	}
+}
+
+func NullAsAbsent() func(c *Compiled) {
+	return func(c *Compiled) {
+		c.NullAsAbsent()
+	}
+}
+
+func LenientIndices() func(c *Compiled) {
+	return func(c *Compiled) {
+		c.LenientIndices()
+	}
+}
+
+func SkipNilIntermediates() func(c *Compiled) {
+	return func(c *Compiled) {
+		c.SkipNilIntermediates()
+	}
+}
+
+func NoCreateSlices() func(c *Compiled) {
+	return func(c *Compiled) {
+		c.NoCreateSlices()
+	}
+}
+
+func NoCreateMaps() func(c *Compiled) {
+	return func(c *Compiled) {
+		c.NoCreateMaps()
+	}
+}
+
+func CreateOnRecursiveMiss() func(c *Compiled) {
+	return func(c *Compiled) {
+		c.CreateOnRecursiveMiss()
+	}
+}
+
+func (c *Compiled) Set(object interface{}, value interface{}) error {
+	_, err := c.set(reflect.ValueOf(object), staticValue(value))
+	return err
+}
+
+// Sets the value only where the path does not already exist, leaving existing
+// values untouched. Missing containers are created as with Set. With
+// NullAsAbsent, null values are also replaced.
+func (c *Compiled) SetIfAbsent(object interface{}, value interface{}) error {
+	withMissing := *c
+	withMissing.missing = new(bool)
+	_, err := withMissing.set(reflect.ValueOf(object), func(old reflect.Value) interface{} {
+		if *withMissing.missing || (c.nullAsAbsent && jsonType(old) == "null") {
+			return value
+		}
+		if old.IsValid() && old.CanInterface() {
+			return old.Interface()
+		}
+		return nil
+	})
+	return err
+}
+
+// Sets the value on every matched element, carrying on past elements that
+// cannot be set. Returns an error for each of those elements, prefixed with the
+// path to the element, or a single error if the path cannot be set at all.
+func (c *Compiled) SetAll(object interface{}, value interface{}) []error {
+	withErrs := *c
+	withErrs.setErrs = &[]error{}
+	withErrs.location = &[]string{}
+	_, err := withErrs.set(reflect.ValueOf(object), staticValue(value))
+	if len(*withErrs.setErrs) > 0 {
+		return *withErrs.setErrs
+	}
+	if err != nil {
+		return []error{err}
+	}
+	return nil
+}
+
+// Sets the value after checking it with validate. The value is shared by every
+// matched path, so it is validated once and nothing is set if validate returns
+// an error, which is returned unchanged.
+func (c *Compiled) SetValidated(object interface{}, value interface{}, validate func(interface{}) error) error {
+	if err := validate(value); err != nil {
+		return err
+	}
+	return c.Set(object, value)
+}
+
+// Sets each matched value to the result of fn, which receives the current value.
+// If fn panics, no more values are set and the panic is passed on.
+func (c *Compiled) SetFunc(object interface{}, fn func(old interface{}) interface{}) error {
+	var callback callbackPanic
+	_, err := c.set(reflect.ValueOf(object), func(old reflect.Value) interface{} {
+		var oldValue interface{}
+		if old.IsValid() && old.CanInterface() {
+			oldValue = old.Interface()
+		}
+		result := oldValue
+		callback.call(func() {
+			result = fn(oldValue)
+		})
+		return result
+	})
+	callback.repanic()
+	return err
+}
+
+// Sets the value on a deep copy of the object, leaving the original untouched.
+// Unexported struct fields cannot be copied and are shared with the original.
+func (c *Compiled) SetCopy(object interface{}, value interface{}) (interface{}, error) {
+	copied := deepCopy(reflect.ValueOf(object))
+	result, err := c.set(copied, staticValue(value))
+	if err != nil {
+		return nil, err
+	}
+	if !result.IsValid() {
+		result = copied
+	}
+	if !result.IsValid() {
+		return nil, nil
+	}
+	return result.Interface(), nil
+}
+
+// Merges the value into the map at each matched path. Nested maps are merged
+// key by key, and all other values, including arrays, replace the existing
+// value. A map merged into an existing value that is not a map replaces it,
+// as does a value that is not a map merged into an existing map.
+func (c *Compiled) Merge(object interface{}, value map[string]interface{}) error {
+	_, err := c.set(reflect.ValueOf(object), func(old reflect.Value) interface{} {
+		if old.IsValid() && old.CanInterface() {
+			if existing, ok := old.Interface().(map[string]interface{}); ok && existing != nil {
+				mergeMaps(existing, value)
+				return existing
+			}
+		}
+		return value
+	})
+	return err
+}
+
+// Recursively merges the keys of src into dst
+func mergeMaps(dst map[string]interface{}, src map[string]interface{}) {
+	for k, v := range src {
+		if srcMap, ok := v.(map[string]interface{}); ok {
+			if dstMap, ok := dst[k].(map[string]interface{}); ok && dstMap != nil {
+				mergeMaps(dstMap, srcMap)
+				continue
+			}
+		}
+		dst[k] = v
+	}
+}
+
+// Appends the value to each matched slice. Missing paths are created as a new
+// slice containing the value.
+func (c *Compiled) Append(object interface{}, value interface{}) error {
+	var appendErr *Error
+	_, err := c.set(reflect.ValueOf(object), func(old reflect.Value) interface{} {
+		slice := old
+		for slice.Kind() == reflect.Interface {
+			slice = slice.Elem()
+		}
+		if !slice.IsValid() {
+			return []interface{}{value}
+		}
+		target := slice
+		if target.Kind() == reflect.Ptr && !target.IsNil() {
+			target = target.Elem()
+		}
+		if target.Kind() != reflect.Slice {
+			appendErr = &Error{Code: TypeMismatch, Msg: fmt.Sprintf("cannot append to type %s", slice.Type().String())}
+			return old.Interface()
+		}
+		elemType := target.Type().Elem()
+		elem := reflect.ValueOf(value)
+		if !elem.IsValid() {
+			elem = reflect.Zero(elemType)
+		}
+		if !elem.Type().AssignableTo(elemType) {
+			appendErr = &Error{Code: TypeMismatch, Msg: fmt.Sprintf("cannot append type %s to type %s", elem.Type().String(), target.Type().String())}
+			return old.Interface()
+		}
+		if target != slice {
+			if !target.CanSet() {
+				appendErr = &Error{Code: TypeMismatch, Msg: fmt.Sprintf("slice is not addressable (%s)", slice.Type().String())}
+				return old.Interface()
+			}
+			target.Set(reflect.Append(target, elem))
+			return slice.Interface()
+		}
+		return reflect.Append(target, elem).Interface()
+	})
+	if err != nil {
+		return err
+	}
+	if appendErr != nil {
+		return appendErr
+	}
+	return nil
+}
+
+// Deletes each matched map key or slice element. The path must end in keys,
+// indexes or a wildcard, and elements after a deleted slice element are moved
+// down. Slices are shortened in place, so a slice at the root must be passed
+// by pointer. Returns a NotFound error if nothing was deleted.
+func (c *Compiled) Delete(object interface{}) error {
+	if c.union != nil || len(c.segments) == 0 {
+		return &Error{Code: InvalidPath, Msg: fmt.Sprintf("cannot delete the path (%s)", c.String())}
+	}
+	last := c.segments[len(c.segments)-1]
+	if !canDelete(last) {
+		return &Error{Code: InvalidPath, Msg: fmt.Sprintf("cannot delete with the segment (%s)", last.raw)}
+	}
+	root := reflect.ValueOf(object)
+	if len(c.segments) == 1 && root.Kind() == reflect.Slice {
+		return &Error{Code: TypeMismatch, Msg: "cannot delete from a slice that is not passed by pointer"}
+	}
+	parent := *c
+	parent.segments = c.segments[:len(c.segments)-1]
+	parent.strictPaths = true
+	var deleted bool
+	var deleteErr *Error
+	_, err := parent.set(root, func(old reflect.Value) interface{} {
+		result, ok, err := deleteFrom(old, last)
+		if err != nil && deleteErr == nil {
+			deleteErr = err
+		}
+		deleted = deleted || ok
+		if !result.IsValid() {
+			return nil
+		}
+		return result.Interface()
+	})
+	if err != nil {
+		return err
+	}
+	if deleteErr != nil {
+		return deleteErr
+	}
+	if !deleted {
+		return &Error{Code: NotFound, Msg: fmt.Sprintf("path not found (%s)", last.raw)}
+	}
+	return nil
+}
+
+// Returns true if the segment selects keys or indexes that can be deleted
+func canDelete(seg segment) bool {
+	if seg.isRecursive || seg.isLength || seg.isSortedKeys || seg.isParent || seg.function != "" || seg.filter != nil || seg.keyFilter != nil {
+		return false
+	}
+	for _, idx := range seg.indexes {
+		if idx.hasStart || idx.hasEnd {
+			return false
+		}
+	}
+	return seg.isKey || seg.isIndex || seg.isWildcard
+}
+
+// Deletes the keys or indexes selected by seg from a map or slice, returning
+// the container and whether anything was deleted
+func deleteFrom(old reflect.Value, seg segment) (reflect.Value, bool, *Error) {
+	container := old
+	for container.Kind() == reflect.Interface {
+		container = container.Elem()
+	}
+	// the value a shortened slice is set on when it cannot be returned
+	target := container
+	if target.Kind() == reflect.Ptr && !target.IsNil() {
+		target = target.Elem()
+	}
+	values := target
+	for values.Kind() == reflect.Interface {
+		values = values.Elem()
+	}
+	switch values.Kind() {
+	case reflect.Map:
+		if values.IsNil() {
+			return old, false, nil
+		}
+		var deleted bool
+		keys := values.MapKeys()
+		if !seg.isWildcard {
+			keys = keys[:0]
+			for _, k := range seg.keys {
+				key := reflect.ValueOf(k)
+				if !key.Type().ConvertibleTo(values.Type().Key()) {
+					continue
+				}
+				keys = append(keys, key.Convert(values.Type().Key()))
+			}
+		}
+		for _, key := range keys {
+			if values.MapIndex(key).IsValid() {
+				values.SetMapIndex(key, reflect.Value{})
+				deleted = true
+			}
+		}
+		return old, deleted, nil
+	case reflect.Slice:
+		remove := make([]bool, values.Len())
+		if seg.isWildcard {
+			for i := range remove {
+				remove[i] = true
+			}
+		}
+		for _, idx := range seg.indexes {
+			i, err := wrapIndex(idx.idx, values.Len(), true)
+			if err == nil {
+				remove[i] = true
+			}
+		}
+		result := reflect.MakeSlice(values.Type(), 0, values.Len())
+		for i, r := range remove {
+			if !r {
+				result = reflect.Append(result, values.Index(i))
+			}
+		}
+		if result.Len() == values.Len() {
+			return old, false, nil
+		}
+		if target != container {
+			if !target.CanSet() {
+				return old, false, &Error{Code: TypeMismatch, Msg: fmt.Sprintf("slice is not addressable (%s)", container.Type().String())}
+			}
+			target.Set(result)
+			return old, true, nil
+		}
+		return result, true, nil
+	}
+	if !container.IsValid() {
+		return old, false, nil
+	}
+	return old, false, &Error{Code: TypeMismatch, Msg: fmt.Sprintf("cannot delete from type %s (%s)", container.Type().String(), seg.raw)}
+}
+
+func (c *Compiled) set(object reflect.Value, value func(reflect.Value) interface{}) (_ reflect.Value, retErr error) {
+	defer recoverError(&retErr)
+	if c.hasFilter && c.query == nil && object.IsValid() {
+		c = c.withQuery(object.Interface())
+	}
+	if c.union != nil {
+		for _, branch := range c.branches() {
+			result, err := branch.set(object, value)
+			if err != nil {
+				return result, err
+			}
+			if result.IsValid() {
+				object = result
+			}
+		}
+		return object, nil
+	}
+	c = c.withNesting()
+	var valueSet bool
+	result, err := c.setJSONValues(object, c.segments, value, &valueSet)
+	if err == nil && !valueSet {
+		// recursive descent through empty containers matches nothing either
+		if i := slices.IndexFunc(c.segments, func(seg segment) bool { return seg.isRecursive }); i != -1 {
+			err = &Error{Code: RecursiveMiss, Msg: fmt.Sprintf("path not found (%s)", c.segments[i].raw)}
+		}
+	}
+	if err != nil {
+		if err.Code != RecursiveMiss {
+			return result, err
+		}
+		if !valueSet {
+			// recursive descent only sets existing keys, so a path that
+			// matches nothing is not found unless it can be created
+			if flat, ok := c.withoutRecursion(); ok && c.createOnRecursiveMiss {
+				if result.IsValid() {
+					object = result
+				}
+				return flat.set(object, value)
+			}
+			return result, &Error{Code: NotFound, Msg: err.Msg}
+		}
+	}
+	return result, nil
+}
+
+// Returns a copy of the path with each recursive segment replaced by the
+// segment it matches, e.g. key..name becomes key.name, for
+// CreateOnRecursiveMiss. Recursive segments that match both keys and indexes
+// cannot be replaced.
+func (c *Compiled) withoutRecursion() (*Compiled, bool) {
+	withoutRecursion := *c
+	withoutRecursion.segments = make([]segment, len(c.segments))
+	var found bool
+	for i, seg := range c.segments {
+		if seg.isRecursive {
+			if seg.isKey && seg.isIndex {
+				return nil, false
+			}
+			seg.isRecursive = false
+			seg.raw = strings.TrimPrefix(seg.raw, ".")
+			found = true
+		}
+		withoutRecursion.segments[i] = seg
+	}
+	return &withoutRecursion, found
+}
+
+func (c *Compiled) Get(object interface{}) (interface{}, error) {
+	return c.GetContext(context.Background(), object)
+}
+
+// Gets the value, stopping the traversal once ctx is done. The context is
+// checked periodically while visiting nodes, and its error is returned if the
+// query is stopped.
+func (c *Compiled) GetContext(ctx context.Context, object interface{}) (interface{}, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if ctx.Done() == nil {
+		// the context can never be done
+		value, _, err := c.GetCount(object)
+		return value, err
+	}
+	withContext := *c
+	withContext.cancel = &canceller{ctx: ctx}
+	value, _, err := withContext.GetCount(object)
+	if e, ok := err.(*Error); ok && e.Code == canceled {
+		return nil, ctx.Err()
+	}
+	return value, err
+}
+
+type canceller struct {
+	ctx     context.Context
+	visits  int
+	stopped bool
+}
+
+// Checks the context every contextCheckInterval visits. Once the context is
+// done, every visit fails so the error is not lost between sibling values.
+func (c *canceller) check() *Error {
+	c.visits += 1
+	if !c.stopped && c.visits%contextCheckInterval == 0 && c.ctx.Err() != nil {
+		c.stopped = true
+	}
+	if c.stopped {
+		return &Error{Code: canceled, Msg: c.ctx.Err().Error()}
+	}
+	return nil
+}
+
+// Gets the value using documents bound for this query only, which can be
+// referenced in filters as $name
+func (c *Compiled) GetWith(object interface{}, options ...func(*QueryOptions)) (interface{}, error) {
+	return c.withQuery(object, options...).Get(object)
+}
+
+// Allows the queried document to be referenced in filters as $name
+func (c *Compiled) Bind(name string) {
+	c.rootName = name
+}
+
+// Returns the matched value(s) along with the number of matched nodes
+func (c *Compiled) GetCount(object interface{}) (interface{}, int, error) {
+	value, err := c.getValues(object)
+	if err != nil {
+		return nil, 0, err
+	}
+	if c.sortResults && c.hasMulti {
+		sortResults(value)
+	}
+	if c.alwaysSlice {
+		return value, len(value), nil
+	}
+	if (!c.hasMulti || c.collapseSingle) && len(value) == 1 {
+		if c.asSlice {
+			return toSlice(value[0]), 1, nil
+		}
+		return value[0], 1, nil
+	}
+	return value, len(value), nil
+}
+
+// Returns the elements of a slice or array value as an interface slice, or a
+// slice containing the value if it is not a slice or array
+func toSlice(value interface{}) []interface{} {
+	if slice, ok := value.([]interface{}); ok {
+		return slice
+	}
+	v := reflect.ValueOf(value)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return []interface{}{value}
+	}
+	result := make([]interface{}, v.Len())
+	for i := range result {
+		result[i] = v.Index(i).Interface()
+	}
+	return result
+}
+
+type walker struct {
+	fn      func(path string, value interface{}) error
+	err     error
+	matched bool
+	// a panic from fn, which stops the walk
+	callback callbackPanic
+	// paths of matches that recursive descent skips
+	skipped map[string]bool
+}
+
+// Calls fn with each matched value as it is found, without collecting the
+// values, and stops at the first error returned by fn, which is returned
+// unchanged. Values are passed to fn before the values nested inside them, and
+// fn can return SkipRecursion to skip recursive descent into a value. Returns
+// a not found error if nothing matched.
+func (c *Compiled) Walk(object interface{}, fn func(value interface{}) error) error {
+	return c.walkValues(object, func(_ string, value interface{}) error {
+		return fn(value)
+	})
+}
+
+// Walks the matched values as with Walk, passing the path to each value in
+// normalized bracket notation, e.g. $['key'][0]
+func (c *Compiled) WalkPaths(object interface{}, fn func(path string, value interface{}) error) error {
+	if c.anySegment(func(s segment) bool { return s.isLength || s.isParent }) {
+		return &Error{Code: InvalidPath, Msg: "cannot walk paths using a length or parent selector"}
+	}
+	return c.walkValues(object, fn)
+}
+
+func (c *Compiled) walkValues(object interface{}, fn func(path string, value interface{}) error) error {
+	withWalk := *c
+	withWalk.walk = &walker{fn: fn, skipped: map[string]bool{}}
+	withWalk.location = &[]string{}
+	_, err := withWalk.getValues(object)
+	withWalk.walk.callback.repanic()
+	if withWalk.walk.err != nil {
+		return withWalk.walk.err
+	}
+	if err != nil && (!notFound(err) || !withWalk.walk.matched) {
+		return err
+	}
+	return nil
+}
+
+// Describes how a path resolved against an object. Paths are in normalized
+// bracket notation, e.g. $['key'][0].
+type Trace struct {
+	// paths of the matched values, in the order they were matched
+	Matched []string
+	// paths of the nodes the rest of the path could not be followed from, e.g.
+	// $['a'] when getting $.a.b from {"a": {}}. Union branches that are not
+	// found are listed even when another branch matched.
+	Missing []string
+}
+
+// Gets the value as with Get, also returning the paths that matched and the
+// paths that could not be followed. The trace is returned with any error, to
+// show where a path that was not found stopped.
+func (c *Compiled) GetTrace(object interface{}) (interface{}, Trace, error) {
+	if c.anySegment(func(s segment) bool { return s.isLength || s.isParent }) {
+		return nil, Trace{}, &Error{Code: InvalidPath, Msg: "cannot trace paths using a length or parent selector"}
+	}
+	withTrace := *c
+	withTrace.trace = &Trace{}
+	withTrace.location = &[]string{}
+	value, err := withTrace.Get(object)
+	return value, *withTrace.trace, err
+}
+
+// Passes a matched value to the walk callback
+func (c *Compiled) walkValue(value interface{}) *Error {
+	if c.structsAsMaps {
+		var err *Error
+		value, err = c.structsToMaps(reflect.ValueOf(value))
+		if err != nil {
+			return err
+		}
+	}
+	c.walk.matched = true
+	path := c.locationPath()
+	var err error
+	c.walk.callback.call(func() {
+		err = c.walk.fn(path, value)
+	})
+	if c.walk.callback.panicked {
+		return &Error{Code: walkStopped, Msg: "walk stopped"}
+	}
+	if err == SkipRecursion {
+		c.walk.skipped[path] = true
+		return nil
+	}
+	if err != nil {
+		c.walk.err = err
+		return &Error{Code: walkStopped, Msg: "walk stopped"}
+	}
+	return nil
+}
+
+// Returns the path to the node being visited
+func (c *Compiled) locationPath() string {
+	return c.root() + strings.Join(*c.location, "")
+}
+
+func (c *Compiled) setMissing(missing bool) {
+	if c.missing != nil {
+		*c.missing = missing
+	}
+}
+
+// Adds a map key to the path of the node being visited. The key is only
+// formatted when locations are tracked.
+func (c *Compiled) enterKey(key reflect.Value) {
+	if c.location != nil {
+		*c.location = append(*c.location, "["+quoteKey(keyString(key))+"]")
+	}
+}
+
+// Adds a struct field to the path of the node being visited, by the name it
+// is matched by
+func (c *Compiled) enterField(object reflect.Value, name string) {
+	if c.location != nil {
+		*c.location = append(*c.location, "["+quoteKey(c.fieldKey(object, name))+"]")
+	}
+}
+
+// Adds an index to the path of the node being visited
+func (c *Compiled) enterIndex(i int) {
+	if c.location != nil {
+		*c.location = append(*c.location, "["+strconv.Itoa(i)+"]")
+	}
+}
+
+func (c *Compiled) leave() {
+	if c.location != nil {
+		*c.location = (*c.location)[:len(*c.location)-1]
+	}
+}
+
+// Records an element that could not be set when collecting errors for SetAll,
+// returning nil so the remaining elements are still set
+func (c *Compiled) collect(err *Error, path []segment) *Error {
+	if err == nil || (err.Code != NotFound && err.Code != TypeMismatch) || c.setErrs == nil {
+		return err
+	}
+	*c.setErrs = append(*c.setErrs, withPath(c.atSegment(err, path), c.locationPath()))
+	return nil
+}
+
+// Returns the first matched value. Map keys are matched in sorted order.
+func (c *Compiled) First(object interface{}) (interface{}, error) {
+	value, err := c.sorted().getValues(object)
+	if err != nil {
+		return nil, err
+	}
+	if len(value) == 0 {
+		return nil, &Error{Code: NotFound, Msg: "no values matched"}
+	}
+	return value[0], nil
+}
+
+// Returns the last matched value. Map keys are matched in sorted order.
+func (c *Compiled) Last(object interface{}) (interface{}, error) {
+	value, err := c.sorted().getValues(object)
+	if err != nil {
+		return nil, err
+	}
+	if len(value) == 0 {
+		return nil, &Error{Code: NotFound, Msg: "no values matched"}
+	}
+	return value[len(value)-1], nil
+}
+
+// Returns the JSON type of each matched value, in the order they are matched
+// with map keys in sorted order. Types are "string", "number", "boolean",
+// "null", "object" or "array".
+func (c *Compiled) InferTypes(object interface{}) ([]string, error) {
+	value, err := c.sorted().getValues(object)
+	if err != nil {
+		return nil, err
+	}
+	types := make([]string, len(value))
+	for i, v := range value {
+		types[i] = jsonType(reflect.ValueOf(v))
+	}
+	return types, nil
+}
+
+// Returns the JSON type that a value would be encoded as
+func jsonType(value reflect.Value) string {
+	for value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			return "null"
+		}
+		value = value.Elem()
+	}
+	if !value.IsValid() {
+		return "null"
+	}
+	if value.Type() == numberType {
+		return "number"
+	}
+	switch value.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Map, reflect.Struct:
+		return "object"
+	case reflect.Slice, reflect.Array:
+		if value.Kind() == reflect.Slice && value.IsNil() {
+			return "null"
+		}
+		return "array"
+	}
+	if value.CanInt() || value.CanUint() || value.CanFloat() {
+		return "number"
+	}
+	return value.Kind().String()
+}
+
+func (c *Compiled) getValues(object interface{}) (_ []interface{}, retErr error) {
+	defer recoverError(&retErr)
+	if c.hasFilter && c.query == nil {
+		c = c.withQuery(object)
+	}
+	c = c.withVisits()
+	if c.union != nil {
+		return c.getUnionValues(object)
+	}
+	if fn := c.aggregate(); fn != "" && c.walk == nil && c.depth == nil && c.key == nil {
+		value, err := c.withoutFunction().aggregateValues(object, c.segments[len(c.segments)-1])
+		if err != nil {
+			return nil, err
+		}
+		return []interface{}{value}, nil
+	}
+	c = c.withNesting().withParents()
+	value, err := c.getJSONValues(object, c.segments)
+	if err != nil {
+		if err.Code != RecursiveMiss {
+			return nil, err
+		}
+		if len(value) == 0 {
+			return nil, &Error{Code: NotFound, Msg: "path not found"}
+		}
+	}
+	if c.structsAsMaps {
+		for i, v := range value {
+			if match, ok := v.(depthMatch); ok {
+				converted, err := c.structsToMaps(reflect.ValueOf(match.value))
+				if err != nil {
+					return nil, err
+				}
+				match.value = converted
+				value[i] = match
+				continue
+			}
+			converted, err := c.structsToMaps(reflect.ValueOf(v))
+			if err != nil {
+				return nil, err
+			}
+			value[i] = converted
+		}
+	}
+	if c.dedupe && c.hasMulti {
+		value = dedupe(value)
+	}
+	return value, nil
+}
+
+// Removes duplicate values, keeping the first occurrence. Values are compared
+// using reflect.DeepEqual, so this is O(n^2) in the number of matched values.
+func dedupe(values []interface{}) []interface{} {
+	result := make([]interface{}, 0, len(values))
+	for _, v := range values {
+		duplicate := false
+		for _, r := range result {
+			if reflect.DeepEqual(v, r) {
+				duplicate = true
+				break
+			}
+		}
+		if !duplicate {
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+// Sorts values with numbers first, then strings, then any other values by
+// their JSON encoding
+func sortResults(values []interface{}) {
+	sort.SliceStable(values, func(i, j int) bool {
+		a, b := values[i], values[j]
+		aRank, bRank := resultRank(a), resultRank(b)
+		if aRank != bRank {
+			return aRank < bRank
+		}
+		switch aRank {
+		case 0:
+			aInt, aOk := toInt(a)
+			bInt, bOk := toInt(b)
+			if aOk && bOk {
+				return aInt < bInt
+			}
+			aNum, _ := toFloat(a)
+			bNum, _ := toFloat(b)
+			return aNum < bNum
+		case 1:
+			return reflect.ValueOf(a).String() < reflect.ValueOf(b).String()
+		}
+		return encodeResult(a) < encodeResult(b)
+	})
+}
+
+func resultRank(value interface{}) int {
+	if _, ok := toFloat(value); ok {
+		return 0
+	}
+	if reflect.ValueOf(value).Kind() == reflect.String {
+		return 1
+	}
+	return 2
+}
+
+func encodeResult(value interface{}) string {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(encoded)
+}
+
+// Gets the value, along with whether the container of the last path segment
+// was found. This tells an empty result, such as a wildcard over an empty
+// array, apart from a path that does not exist. Unless strict paths are
+// enabled, a value that cannot be found is returned as nil, or an empty slice
+// for paths that can match multiple values, instead of an error.
+func (c *Compiled) GetE(object interface{}) (interface{}, bool, error) {
+	withReached := *c
+	withReached.reached = new(bool)
+	value, err := withReached.Get(object)
+	// the root path has no container
+	reached := *withReached.reached || (len(c.segments) == 0 && c.union == nil)
+	if err != nil {
+		if !notFound(err) || c.strictPaths {
+			return nil, reached, err
+		}
+		if c.hasMulti || c.alwaysSlice {
+			return []interface{}{}, reached, nil
+		}
+		return nil, reached, nil
+	}
+	return value, reached, nil
+}
+
+// Returns the matched values grouped by the depth they were found at. The
+// depth is the number of keys and indexes between the root and the value, so
+// "$..key" matches of a top-level key are found at depth 1.
+func (c *Compiled) GetByDepth(object interface{}) (map[int][]interface{}, error) {
+	withDepth := *c
+	withDepth.depth = new(int)
+	values, err := withDepth.getValues(object)
+	if err != nil {
+		return nil, err
+	}
+	result := map[int][]interface{}{}
+	for _, v := range values {
+		match := v.(depthMatch)
+		result[match.depth] = append(result[match.depth], match.value)
+	}
+	return result, nil
+}
+
+// A matched value and its depth, returned in place of the value while depth
+// is tracked
+type depthMatch struct {
+	value interface{}
+	depth int
+}
+
+// Wraps a matched value with its depth, if depth is tracked
+func (c *Compiled) match(value interface{}) interface{} {
+	if c.key != nil {
+		return *c.key
+	}
+	if c.depth == nil {
+		return value
+	}
+	return depthMatch{value, *c.depth}
+}
+
+// Returns the key of each matched value: the map key, the struct field name
+// (or tag name when a struct tag is used), or the index within a slice. Keys
+// are in the order values are matched, with map keys in sorted order.
+func (c *Compiled) Keys(object interface{}) ([]string, error) {
+	if c.anySegment(func(s segment) bool { return s.isLength || s.isParent }) {
+		return nil, &Error{Code: InvalidPath, Msg: "cannot get keys using a length or parent selector"}
+	}
+	if c.union == nil && len(c.segments) == 0 {
+		return []string{}, nil
+	}
+	withKeys := *c.sorted()
+	withKeys.key = new(string)
+	values, err := withKeys.getValues(object)
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, len(values))
+	for i, v := range values {
+		keys[i] = v.(string)
+	}
+	return keys, nil
+}
+
+// Returns a copy of the compiled path that counts the nodes visited by a
+// single query, if the number of visits is limited
+func (c *Compiled) withVisits() *Compiled {
+	if c.maxVisits <= 0 || c.visits != nil {
+		return c
+	}
+	withVisits := *c
+	withVisits.visits = new(int)
+	return &withVisits
+}
+
+// Counts a visited node, returning an error once the limit is exceeded
+func (c *Compiled) visit() *Error {
+	if c.cancel != nil {
+		if err := c.cancel.check(); err != nil {
+			return err
+		}
+	}
+	if c.visits == nil {
+		return nil
+	}
+	*c.visits += 1
+	if *c.visits > c.maxVisits {
+		return &Error{Code: LimitExceeded, Msg: fmt.Sprintf("visited more than %d nodes", c.maxVisits)}
+	}
+	return nil
+}
+
+// Returns a copy that tracks the depth of recursive descent for a single
+// query. Other paths are limited by their number of segments.
+func (c *Compiled) withNesting() *Compiled {
+	if c.nesting != nil || !slices.ContainsFunc(c.segments, func(s segment) bool { return s.isRecursive }) {
+		return c
+	}
+	withNesting := *c
+	withNesting.nesting = &nesting{}
+	return &withNesting
+}
+
+// Returns a copy that tracks the containers of the node being visited for a
+// single query, if the path climbs to a parent
+func (c *Compiled) withParents() *Compiled {
+	if c.parents != nil || !slices.ContainsFunc(c.segments, func(s segment) bool { return s.isParent }) {
+		return c
+	}
+	withParents := *c
+	withParents.parents = &[]reflect.Value{}
+	return &withParents
+}
+
+// Gets values from the container of the current node, which is removed from
+// the tracked containers while the rest of the path is followed
+func (c *Compiled) getParentValues(path []segment) ([]interface{}, *Error) {
+	parents := *c.parents
+	if len(parents) == 0 {
+		return nil, &Error{Code: NotFound, Msg: fmt.Sprintf("cannot climb above the root (%s)", path[0].raw)}
+	}
+	// limit the capacity so containers added by the rest of the path do not
+	// overwrite the parent
+	*c.parents = parents[: len(parents)-1 : len(parents)-1]
+	defer func() { *c.parents = parents }()
+	return c.getNestedValues(parents[len(parents)-1], path[1:])
+}
+
+type nesting struct {
+	depth int
+	// once exceeded, the rest of the query fails so the error is not lost
+	// between sibling values
+	exceeded bool
+}
+
+// Counts a level of nesting, returning an error once the depth limit is
+// exceeded. Each call must be followed by ascend.
+func (c *Compiled) descend() *Error {
+	if c.nesting == nil {
+		return nil
+	}
+	c.nesting.depth += 1
+	limit := c.maxDepth
+	if limit <= 0 {
+		limit = defaultMaxDepth
+	}
+	if c.nesting.exceeded || c.nesting.depth > limit {
+		c.nesting.exceeded = true
+		return &Error{Code: DepthExceeded, Msg: fmt.Sprintf("exceeded the maximum depth of %d", limit)}
+	}
+	return nil
+}
+
+func (c *Compiled) ascend() {
+	if c.nesting != nil {
+		c.nesting.depth -= 1
+	}
+}
+
+func (c *Compiled) sorted() *Compiled {
+	if c.sortKeys {
+		return c
+	}
+	sorted := *c
+	sorted.sortKeys = true
+	return &sorted
+}
+
+// Functions that can end a path, returning a single value from all of the
+// values matched before them
+var aggregateFuncs = []string{"count", "min", "max", "sum", "avg"}
+
+// Returns the aggregate function ending the path, if any
+func (c *Compiled) aggregate() string {
+	if len(c.segments) == 0 {
+		return ""
+	}
+	fn := c.segments[len(c.segments)-1].function
+	if !slices.Contains(aggregateFuncs, fn) {
+		return ""
+	}
+	return fn
+}
+
+// Returns a copy of the path without the function ending it
+func (c *Compiled) withoutFunction() *Compiled {
+	withoutFunction := *c
+	withoutFunction.segments = c.segments[:len(c.segments)-1]
+	withoutFunction.hasMulti = true
+	return &withoutFunction
+}
+
+// Applies the aggregate function of seg to the values matched by the path.
+// Numbers are summed and averaged as float64, while min and max return the
+// matched value.
+func (c *Compiled) aggregateValues(object interface{}, seg segment) (interface{}, error) {
+	if seg.function == "count" {
+		return c.Count(object)
+	}
+	values, err := c.getValues(object)
+	if err != nil {
+		return nil, err
+	}
+	var result interface{}
+	var sum float64
+	for _, v := range values {
+		num, ok := toFloat(v)
+		if !ok {
+			return nil, &Error{Code: TypeMismatch, Msg: fmt.Sprintf("cannot get the %s of non-numeric value %s (%s)", seg.function, encodeResult(v), seg.raw)}
+		}
+		sum += num
+		switch {
+		case result == nil:
+			result = v
+		case seg.function == "min" && compareValues(v, "<", result):
+			result = v
+		case seg.function == "max" && compareValues(v, ">", result):
+			result = v
+		}
+	}
+	switch seg.function {
+	case "sum":
+		return sum, nil
+	case "avg":
+		if len(values) == 0 {
+			return nil, &Error{Code: NotFound, Msg: fmt.Sprintf("no values to get the avg of (%s)", seg.raw)}
+		}
+		return sum / float64(len(values)), nil
+	}
+	if result == nil {
+		return nil, &Error{Code: NotFound, Msg: fmt.Sprintf("no values to get the %s of (%s)", seg.function, seg.raw)}
+	}
+	return result, nil
+}
+
+// Returns the number of values matched by the path. Unless strict paths are
+// enabled, a path that cannot be found has a count of 0. With Dedupe,
+// duplicate values are only counted once.
+func (c *Compiled) Count(object interface{}) (_ int, retErr error) {
+	defer recoverError(&retErr)
+	if c.aggregate() != "" {
+		// the aggregate itself is the only value
+		if _, err := c.getValues(object); err != nil {
+			return 0, err
+		}
+		return 1, nil
+	}
+	c = c.withVisits()
+	if c.union != nil && c.dedupe {
+		// duplicates can come from different branches, so the values are needed
+		values, err := c.getUnionValues(object)
+		if err != nil {
+			if notFound(err) && !c.strictPaths {
+				return 0, nil
+			}
+			return 0, err
+		}
+		return len(values), nil
+	}
+	if c.union != nil {
+		var count int
+		for _, branch := range c.branches() {
+			n, err := branch.Count(object)
+			if err != nil {
+				return 0, err
+			}
+			count += n
+		}
+		return count, nil
+	}
+	c = c.withNesting().withParents()
+	value, err := c.getJSONValues(object, c.segments)
+	if err != nil && err.Code != RecursiveMiss {
+		if notFound(err) && !c.strictPaths {
+			return 0, nil
+		}
+		return 0, err
+	}
+	if err != nil && len(value) == 0 && c.strictPaths {
+		return 0, &Error{Code: NotFound, Msg: "path not found"}
+	}
+	if c.dedupe && c.hasMulti {
+		value = dedupe(value)
+	}
+	return len(value), nil
+}
+
+// Returns true if the path matches at least one value. A path that cannot be
+// found is not an error, even with strict paths.
+func (c *Compiled) Exists(object interface{}) (bool, error) {
+	count, err := c.Count(object)
+	if err != nil {
+		if notFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// Returns the default value if the path cannot be found, or nil for any other
+// error such as an exceeded limit
+func (c *Compiled) GetOr(object interface{}, def interface{}) interface{} {
+	value, err := c.Get(object)
+	if err != nil {
+		if notFound(err) {
+			return def
+		}
+		return nil
+	}
+	return value
+}
+
+// Returns exactly n matched values, dropping any extra values and padding with
+// fill if fewer matched. Values are in the order they are matched, with map
+// keys in sorted order. Unless strict paths are enabled, a path that cannot be
+// found returns n fill values.
+func (c *Compiled) GetAllOr(object interface{}, n int, fill interface{}) ([]interface{}, error) {
+	value, err := c.sorted().getValues(object)
+	if err != nil {
+		if !notFound(err) || c.strictPaths {
+			return nil, err
+		}
+		value = nil
+	}
+	if n < 0 {
+		n = 0
+	}
+	result := make([]interface{}, n)
+	for i := range result {
+		if i < len(value) {
+			result[i] = value[i]
+		} else {
+			result[i] = fill
+		}
+	}
+	return result, nil
+}
+
+func Set(object interface{}, path string, value interface{}, options ...func(*Compiled)) error {
+	compiled, err := compileCached(path, options...)
+	if err != nil {
+		return err
+	}
+	return compiled.Set(object, value)
+}
 
-	// only allow setting values on existing paths
-	strictPaths bool
-	// query struct based off a tag instead of field names
-	structTag    string
-	structTagSet bool
+func SetIfAbsent(object interface{}, path string, value interface{}, options ...func(*Compiled)) error {
+	compiled, err := compileCached(path, options...)
+	if err != nil {
+		return err
+	}
+	return compiled.SetIfAbsent(object, value)
 }
 
-type segment struct {
-	raw         string
-	keys        []string
-	keysRefl    []reflect.Value
-	indexes     []index
-	isKey       bool
-	isIndex     bool
-	isWildcard  bool
-	isRecursive bool
-	isMulti     bool
+func SetAll(object interface{}, path string, value interface{}, options ...func(*Compiled)) []error {
+	compiled, err := compileCached(path, options...)
+	if err != nil {
+		return []error{err}
+	}
+	return compiled.SetAll(object, value)
 }
 
-type index struct {
-	idx      int
-	start    int
-	hasStart bool
-	end      int
-	hasEnd   bool
+func SetValidated(object interface{}, path string, value interface{}, validate func(interface{}) error, options ...func(*Compiled)) error {
+	compiled, err := compileCached(path, options...)
+	if err != nil {
+		return err
+	}
+	return compiled.SetValidated(object, value, validate)
 }
 
-type Error struct {
-	Code string
-	Msg  string
+// Sets a separate value for each path. All paths are compiled before any value
+// is set, so an invalid path leaves the object unchanged. Values are set in
+// sorted path order and setting stops at the first error, which can leave the
+// object partially updated.
+func SetMany(object interface{}, patches map[string]interface{}, options ...func(*Compiled)) error {
+	paths := make([]string, 0, len(patches))
+	for path := range patches {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	compiled := make([]*Compiled, len(paths))
+	for i, path := range paths {
+		c, err := compileCached(path, options...)
+		if err != nil {
+			return withPath(err, path)
+		}
+		compiled[i] = c
+	}
+	for i, c := range compiled {
+		if err := c.Set(object, patches[paths[i]]); err != nil {
+			return withPath(err, paths[i])
+		}
+	}
+	return nil
 }
 
-func (e *Error) Error() string {
-	return fmt.Sprintf("%s: %s", e.Code, e.Msg)
+// Gets the value of each path, keyed by the path. All paths are compiled
+// before any value is got, and an invalid path returns an error. Unless strict
+// paths are enabled, paths that cannot be found have a nil value.
+func GetMany(object interface{}, paths []string, options ...func(*Compiled)) (map[string]interface{}, error) {
+	compiled := make([]*Compiled, len(paths))
+	for i, path := range paths {
+		c, err := compileCached(path, options...)
+		if err != nil {
+			return nil, withPath(err, path)
+		}
+		compiled[i] = c
+	}
+	result := make(map[string]interface{}, len(paths))
+	for i, c := range compiled {
+		value, err := c.Get(object)
+		if err != nil {
+			if !notFound(err) || c.strictPaths {
+				return nil, withPath(err, paths[i])
+			}
+			value = nil
+		}
+		result[paths[i]] = value
+	}
+	return result, nil
 }
 
-const (
-	NotFound      = "not_found"
-	InvalidPath   = "invalid_path"
-	RecursiveMiss = "recursive_miss"
-)
+func SetCopy(object interface{}, path string, value interface{}, options ...func(*Compiled)) (interface{}, error) {
+	compiled, err := compileCached(path, options...)
+	if err != nil {
+		return nil, err
+	}
+	return compiled.SetCopy(object, value)
+}
 
-func (c *Compiled) RawPath() string {
-	return c.raw
+func SetFunc(object interface{}, path string, fn func(old interface{}) interface{}, options ...func(*Compiled)) error {
+	compiled, err := compileCached(path, options...)
+	if err != nil {
+		return err
+	}
+	return compiled.SetFunc(object, fn)
 }
 
-func (c *Compiled) EnableStrictPaths() {
-	c.strictPaths = true
+func Merge(object interface{}, path string, value map[string]interface{}, options ...func(*Compiled)) error {
+	compiled, err := compileCached(path, options...)
+	if err != nil {
+		return err
+	}
+	return compiled.Merge(object, value)
 }
 
-func (c *Compiled) UseStructTag(tag string) {
-	c.structTag = tag
-	c.structTagSet = true
+func Append(object interface{}, path string, value interface{}, options ...func(*Compiled)) error {
+	compiled, err := compileCached(path, options...)
+	if err != nil {
+		return err
+	}
+	return compiled.Append(object, value)
 }
 
-func EnableStrictPaths() func(c *Compiled) {
-	return func(c *Compiled) {
-		c.EnableStrictPaths()
+func Delete(object interface{}, path string, options ...func(*Compiled)) error {
+	compiled, err := compileCached(path, options...)
+	if err != nil {
+		return err
 	}
+	return compiled.Delete(object)
 }
 
-func UseStructTag(tag string) func(c *Compiled) {
-	return func(c *Compiled) {
-		c.UseStructTag(tag)
+func Get(object interface{}, path string, options ...func(*Compiled)) (interface{}, error) {
+	compiled, err := compileCached(path, options...)
+	if err != nil {
+		return nil, err
 	}
+	return compiled.Get(object)
 }
 
-func (c *Compiled) Set(object interface{}, value interface{}) error {
-	var valueSet bool
-	_, err := c.setNestedValues(reflect.ValueOf(object), nil, c.segments, value, &valueSet)
+func GetContext(ctx context.Context, object interface{}, path string, options ...func(*Compiled)) (interface{}, error) {
+	compiled, err := compileCached(path, options...)
 	if err != nil {
-		if err.Code != RecursiveMiss {
-			return err
-		}
-		if !valueSet {
-			return &Error{NotFound, err.Msg}
-		}
+		return nil, err
 	}
-	return nil
+	return compiled.GetContext(ctx, object)
 }
 
-func (c *Compiled) Get(object interface{}) (interface{}, error) {
-	value, err := c.getNestedValues(reflect.ValueOf(object), c.segments)
+func GetCount(object interface{}, path string, options ...func(*Compiled)) (interface{}, int, error) {
+	compiled, err := compileCached(path, options...)
 	if err != nil {
-		if err.Code != RecursiveMiss {
-			return nil, err
-		}
-		if len(value) == 0 {
-			return nil, &Error{NotFound, "path not found"}
+		return nil, 0, err
+	}
+	return compiled.GetCount(object)
+}
+
+func GetTrace(object interface{}, path string, options ...func(*Compiled)) (interface{}, Trace, error) {
+	compiled, err := compileCached(path, options...)
+	if err != nil {
+		return nil, Trace{}, err
+	}
+	return compiled.GetTrace(object)
+}
+
+func GetOr(object interface{}, path string, def interface{}, options ...func(*Compiled)) interface{} {
+	compiled, err := compileCached(path, options...)
+	if err != nil {
+		return nil
+	}
+	return compiled.GetOr(object, def)
+}
+
+func GetAllOr(object interface{}, path string, n int, fill interface{}, options ...func(*Compiled)) ([]interface{}, error) {
+	compiled, err := compileCached(path, options...)
+	if err != nil {
+		return nil, err
+	}
+	return compiled.GetAllOr(object, n, fill)
+}
+
+func GetE(object interface{}, path string, options ...func(*Compiled)) (interface{}, bool, error) {
+	compiled, err := compileCached(path, options...)
+	if err != nil {
+		return nil, false, err
+	}
+	return compiled.GetE(object)
+}
+
+// Gets the single value matched by the path as type T. Returns the zero value
+// of T with a TypeMismatch error if the value is not a T, or an InvalidPath
+// error if the path can match multiple values. Numbers decoded by
+// encoding/json are float64, or json.Number when decoded with UseNumber.
+func GetTyped[T any](object interface{}, path string, options ...func(*Compiled)) (T, error) {
+	var zero T
+	compiled, err := compileCached(path, options...)
+	if err != nil {
+		return zero, err
+	}
+	if compiled.IsMulti() {
+		return zero, &Error{Code: InvalidPath, Msg: fmt.Sprintf("path can match multiple values (%s)", path)}
+	}
+	value, err := compiled.Get(object)
+	if err != nil {
+		return zero, err
+	}
+	typeOf := reflect.TypeOf(&zero).Elem()
+	if value == nil {
+		switch typeOf.Kind() {
+		case reflect.Ptr, reflect.Interface, reflect.Map, reflect.Slice:
+			return zero, nil
 		}
 	}
-	if !c.hasMulti && len(value) == 1 {
-		return value[0], nil
+	typed, ok := value.(T)
+	if !ok {
+		return zero, &Error{Code: TypeMismatch, Msg: fmt.Sprintf("cannot convert type %T to type %s", value, typeOf.String())}
 	}
-	return value, nil
+	return typed, nil
 }
 
-func Set(object interface{}, path string, value interface{}, options ...func(*Compiled)) error {
-	compiled, err := Compile(path, options...)
+func InferTypes(object interface{}, path string, options ...func(*Compiled)) ([]string, error) {
+	compiled, err := compileCached(path, options...)
+	if err != nil {
+		return nil, err
+	}
+	return compiled.InferTypes(object)
+}
+
+func Walk(object interface{}, path string, fn func(value interface{}) error, options ...func(*Compiled)) error {
+	compiled, err := compileCached(path, options...)
 	if err != nil {
 		return err
 	}
-	return compiled.Set(object, value)
+	return compiled.Walk(object, fn)
 }
 
-func Get(object interface{}, path string, options ...func(*Compiled)) (interface{}, error) {
-	compiled, err := Compile(path, options...)
+func WalkPaths(object interface{}, path string, fn func(path string, value interface{}) error, options ...func(*Compiled)) error {
+	compiled, err := compileCached(path, options...)
+	if err != nil {
+		return err
+	}
+	return compiled.WalkPaths(object, fn)
+}
+
+func Keys(object interface{}, path string, options ...func(*Compiled)) ([]string, error) {
+	compiled, err := compileCached(path, options...)
 	if err != nil {
 		return nil, err
 	}
-	return compiled.Get(object)
+	return compiled.Keys(object)
+}
+
+func GetByDepth(object interface{}, path string, options ...func(*Compiled)) (map[int][]interface{}, error) {
+	compiled, err := compileCached(path, options...)
+	if err != nil {
+		return nil, err
+	}
+	return compiled.GetByDepth(object)
+}
+
+func Count(object interface{}, path string, options ...func(*Compiled)) (int, error) {
+	compiled, err := compileCached(path, options...)
+	if err != nil {
+		return 0, err
+	}
+	return compiled.Count(object)
+}
+
+func Exists(object interface{}, path string, options ...func(*Compiled)) (bool, error) {
+	compiled, err := compileCached(path, options...)
+	if err != nil {
+		return false, err
+	}
+	return compiled.Exists(object)
+}
+
+func First(object interface{}, path string, options ...func(*Compiled)) (interface{}, error) {
+	compiled, err := compileCached(path, options...)
+	if err != nil {
+		return nil, err
+	}
+	return compiled.First(object)
 }
 
-func (c *Compiled) setNestedValues(object reflect.Value, objectType reflect.Type, path []segment, value interface{}, valueSet *bool) (reflect.Value, *Error) {
+func Last(object interface{}, path string, options ...func(*Compiled)) (interface{}, error) {
+	compiled, err := compileCached(path, options...)
+	if err != nil {
+		return nil, err
+	}
+	return compiled.Last(object)
+}
+
+func (c *Compiled) setNestedValues(object reflect.Value, objectType reflect.Type, path []segment, value func(reflect.Value) interface{}, valueSet *bool) (reflect.Value, *Error) {
+	result, err := c.setSegment(object, objectType, path, value, valueSet)
+	return result, c.atSegment(err, path)
+}
+
+// Sets the value at the first segment of path, and recursively at the rest
+func (c *Compiled) setSegment(object reflect.Value, objectType reflect.Type, path []segment, value func(reflect.Value) interface{}, valueSet *bool) (reflect.Value, *Error) {
 	var err *Error
 	var temp reflect.Value
 
 	final := len(path) == 0
 	if final {
 		*valueSet = true
-		return reflect.ValueOf(value), nil
+		if !object.IsValid() && objectType != nil {
+			object = reflect.Zero(objectType)
+		}
+		return reflect.ValueOf(value(object)), nil
 	}
 	seg := path[0]
 	fullKey := seg.raw
+	// the value must already exist when setting the last segment, either with
+	// strict paths or in update only mode
+	strict := c.strictPaths || (c.updateOnly && len(path) == 1 && !seg.isRecursive)
+
+	if seg.function != "" {
+		return temp, &Error{Code: InvalidPath, Msg: fmt.Sprintf("cannot set a value using %s (%s)", strings.TrimLeft(fullKey, "."), fullKey)}
+	}
+	if seg.isLength {
+		return temp, &Error{Code: InvalidPath, Msg: fmt.Sprintf("cannot set a value using a length selector (%s)", fullKey)}
+	}
+	if seg.isParent {
+		return temp, &Error{Code: InvalidPath, Msg: fmt.Sprintf("cannot set a value using a parent selector (%s)", fullKey)}
+	}
 
 	if !object.IsValid() && objectType != nil {
+		if !c.canCreate(objectType) {
+			return temp, &Error{Code: NotFound, Msg: fmt.Sprintf("path not found (%s)", fullKey)}
+		}
 		object = initNewValue(objectType).Elem()
 	}
 
@@ -153,8 +2164,8 @@ func (c *Compiled) setNestedValues(object reflect.Value, objectType reflect.Type
 		if objectRef.Kind() == reflect.Ptr {
 			derefenced = true
 			if objectRef.IsNil() {
-				if c.strictPaths {
-					return temp, &Error{NotFound, fmt.Sprintf("path not found (%s)", fullKey)}
+				if strict || !c.canCreate(objectRef.Type()) {
+					return temp, &Error{Code: NotFound, Msg: fmt.Sprintf("path not found (%s)", fullKey)}
 				}
 				objectRef.Set(initNewValue(objectRef.Type().Elem()))
 			}
@@ -163,20 +2174,29 @@ func (c *Compiled) setNestedValues(object reflect.Value, objectType reflect.Type
 	}
 
 	if objectRef.IsValid() && objectRef.IsZero() {
-		if c.strictPaths {
-			return temp, &Error{NotFound, fmt.Sprintf("path not found (%s)", fullKey)}
+		if c.strictPaths || (strict && isNil(objectRef)) || !c.canCreate(objectRef.Type()) {
+			return temp, &Error{Code: NotFound, Msg: fmt.Sprintf("path not found (%s)", fullKey)}
 		}
-		if !objectRef.CanSet() {
-			return temp, &Error{NotFound, fmt.Sprintf("object is not addressable (%s)", fullKey)}
+		if objectRef.CanSet() {
+			objectRef.Set(initNewValue(objectRef.Type()).Elem())
+		} else if !derefenced && object.Kind() == reflect.Interface {
+			// a typed value held in an interface is replaced by a new value of
+			// the same type, which is assigned back by the caller
+			objectRef = initNewValue(objectRef.Type()).Elem()
+		} else {
+			return temp, &Error{Code: NotFound, Msg: fmt.Sprintf("object is not addressable (%s)", fullKey)}
 		}
-		objectRef.Set(initNewValue(objectRef.Type()).Elem())
 	}
 
-	switch objectRef.Kind() {
+	kind := objectRef.Kind()
+	if (seg.isRecursive || seg.isWildcard) && objectRef.IsValid() && isOpaque(objectRef.Type()) {
+		kind = reflect.Invalid
+	}
+	switch kind {
 	case reflect.Map:
 		var keys []reflect.Value
 		if !objectRef.IsValid() {
-			return temp, &Error{NotFound, fmt.Sprintf("map invalid (%s)", fullKey)}
+			return temp, &Error{Code: NotFound, Msg: fmt.Sprintf("map invalid (%s)", fullKey)}
 		}
 		elemType := objectRef.Type().Elem()
 		keys, err = c.mapKeys(objectRef, seg)
@@ -186,8 +2206,15 @@ func (c *Compiled) setNestedValues(object reflect.Value, objectType reflect.Type
 
 		for _, k := range keys {
 			nextObject := objectRef.MapIndex(k)
-			if c.strictPaths && !nextObject.IsValid() {
-				return temp, &Error{NotFound, fmt.Sprintf("key does not exist (%s)", fullKey)}
+			c.setMissing(!nextObject.IsValid())
+			c.enterKey(k)
+			if strict && !nextObject.IsValid() {
+				err = c.collect(&Error{Code: NotFound, Msg: fmt.Sprintf("key does not exist (%s)", fullKey)}, path)
+				c.leave()
+				if err != nil {
+					return temp, err
+				}
+				continue
 			}
 			err = c.setCommon(nextObject, path, seg, value, valueSet, elemType,
 				func(val reflect.Value) *Error {
@@ -195,9 +2222,11 @@ func (c *Compiled) setNestedValues(object reflect.Value, objectType reflect.Type
 					return nil
 				},
 				func() bool {
-					return contains(seg.keysRefl, k)
+					return c.matchesKey(seg, k, nextObject)
 				},
 			)
+			err = c.collect(err, path)
+			c.leave()
 		}
 
 	case reflect.Struct:
@@ -208,15 +2237,17 @@ func (c *Compiled) setNestedValues(object reflect.Value, objectType reflect.Type
 			return temp, err
 		}
 		for _, f := range fields {
-			nextObject := objectRef.FieldByName(f)
+			nextObject := c.structField(objectRef, f, !c.strictPaths)
 			if !nextObject.IsValid() {
-				return temp, &Error{NotFound, fmt.Sprintf("field does not exist (%s)", seg.raw)}
+				return temp, &Error{Code: NotFound, Msg: fmt.Sprintf("field does not exist (%s)", seg.raw)}
 			}
-			elemType, _ := objectRef.Type().FieldByName(f)
-			err = c.setCommon(nextObject, path, seg, value, valueSet, elemType.Type,
+			elemType := c.structInfo(objectRef.Type()).byName[f].typ
+			c.setMissing(false)
+			c.enterField(objectRef, f)
+			err = c.setCommon(nextObject, path, seg, value, valueSet, elemType,
 				func(val reflect.Value) *Error {
 					if !nextObject.CanSet() {
-						return &Error{NotFound, fmt.Sprintf("struct field is not addressable (%s)", fullKey)}
+						return &Error{Code: NotFound, Msg: fmt.Sprintf("struct field is not addressable (%s)", fullKey)}
 					}
 					nextObject.Set(val)
 					return nil
@@ -225,26 +2256,34 @@ func (c *Compiled) setNestedValues(object reflect.Value, objectType reflect.Type
 					return slices.Contains(segFields, f)
 				},
 			)
+			err = c.collect(err, path)
+			c.leave()
 		}
 
 	case reflect.Slice, reflect.Array:
 		var idxs []int
 		var segIdxs []int
 		elemType := objectRef.Type().Elem()
-		idxs, segIdxs, err = c.sliceIndexes(objectRef, seg, c.strictPaths)
+		// arrays have a fixed length and cannot be grown
+		idxs, segIdxs, err = c.sliceIndexes(objectRef, seg, strict || kind == reflect.Array, false)
 		if err != nil {
 			return temp, err
 		}
-		objectRef = fillSlice(objectRef, idxs[len(idxs)-1])
+		length := objectRef.Len()
+		if len(idxs) > 0 {
+			objectRef = fillSlice(objectRef, idxs[len(idxs)-1])
+		}
 		for _, i := range idxs {
 			nextObject := objectRef.Index(i)
 			if !nextObject.IsValid() {
-				return temp, &Error{NotFound, fmt.Sprintf("index out of range (%d)", i)}
+				return temp, &Error{Code: NotFound, Msg: fmt.Sprintf("index out of range (%d)", i)}
 			}
+			c.setMissing(i >= length)
+			c.enterIndex(i)
 			err = c.setCommon(nextObject, path, seg, value, valueSet, elemType,
 				func(val reflect.Value) *Error {
 					if !nextObject.CanSet() {
-						return &Error{NotFound, fmt.Sprintf("slice index is not addressable (%s)", fullKey)}
+						return &Error{Code: NotFound, Msg: fmt.Sprintf("slice index is not addressable (%s)", fullKey)}
 					}
 					nextObject.Set(val)
 					return nil
@@ -253,53 +2292,300 @@ func (c *Compiled) setNestedValues(object reflect.Value, objectType reflect.Type
 					return slices.Contains(segIdxs, i)
 				},
 			)
+			err = c.collect(err, path)
+			c.leave()
+		}
+
+	default:
+		if seg.isRecursive {
+			return temp, &Error{Code: RecursiveMiss, Msg: fmt.Sprintf("path not found (%s)", fullKey)}
+		}
+		if strict || seg.isWildcard || seg.keyFilter != nil || seg.filter != nil || seg.isSortedKeys {
+			return temp, &Error{Code: NotFound, Msg: fmt.Sprintf("path not found (%s)", fullKey)}
+		}
+		if (seg.isIndex && c.noCreateSlices) || (!seg.isIndex && c.noCreateMaps) {
+			return temp, &Error{Code: NotFound, Msg: fmt.Sprintf("path not found (%s)", fullKey)}
+		}
+		if seg.isIndex {
+			new := reflect.ValueOf([]interface{}{})
+			parsed := []int{0}
+			if !c.appendsIndex(seg, 0) {
+				if err := c.checkGrowth(seg.indexes, 0); err != nil {
+					return temp, err
+				}
+				parsed, err = parseIndexes(seg.indexes, 0, false, false)
+				if err != nil {
+					return temp, err
+				}
+			}
+			if len(parsed) == 0 {
+				return temp, nil
+			}
+			new = fillSlice(new, parsed[len(parsed)-1])
+			c.setMissing(true)
+			for _, i := range parsed {
+				nextObject := new.Index(i)
+				temp, err = c.setNestedValues(nextObject, nil, path[1:], value, valueSet)
+				if err != nil {
+					return temp, err
+				}
+				if temp.IsValid() {
+					nextObject.Set(temp)
+				}
+			}
+			return new, err
+
+		} else {
+			new := reflect.ValueOf(map[string]interface{}{})
+			c.setMissing(true)
+			for _, k := range seg.keysRefl {
+				temp, err = c.setNestedValues(new.MapIndex(k), nil, path[1:], value, valueSet)
+				if err != nil {
+					return temp, err
+				}
+				if temp.IsValid() {
+					new.SetMapIndex(k, temp)
+				}
+			}
+			return new, err
+		}
+	}
+
+	if derefenced {
+		return object, err
+	}
+	return objectRef, err
+}
+
+func staticValue(value interface{}) func(reflect.Value) interface{} {
+	return func(reflect.Value) interface{} {
+		return value
+	}
+}
+
+// Recursively copies maps, slices, arrays, pointers and exported struct fields.
+// Pointers, maps and slices that are shared in the original are shared in the
+// copy, so cyclic values are copied with the same cycles.
+func deepCopy(src reflect.Value) reflect.Value {
+	return copier{}.copy(src)
+}
+
+// A pointer, map or slice, identified by its address, type and length
+type copyRef struct {
+	ptr uintptr
+	typ reflect.Type
+	len int
+}
+
+// Copies of the references visited by deepCopy
+type copier map[copyRef]reflect.Value
+
+func (c copier) copy(src reflect.Value) reflect.Value {
+	if !src.IsValid() {
+		return src
+	}
+	switch src.Kind() {
+	case reflect.Ptr:
+		if src.IsNil() {
+			return reflect.Zero(src.Type())
+		}
+		ref := copyRef{src.Pointer(), src.Type(), 0}
+		if dst, ok := c[ref]; ok {
+			return dst
 		}
+		dst := reflect.New(src.Type().Elem())
+		c[ref] = dst
+		dst.Elem().Set(c.copy(src.Elem()))
+		return dst
+	case reflect.Interface:
+		dst := reflect.New(src.Type()).Elem()
+		if !src.IsNil() {
+			dst.Set(c.copy(src.Elem()))
+		}
+		return dst
+	case reflect.Map:
+		if src.IsNil() {
+			return reflect.Zero(src.Type())
+		}
+		ref := copyRef{src.Pointer(), src.Type(), 0}
+		if dst, ok := c[ref]; ok {
+			return dst
+		}
+		dst := reflect.MakeMapWithSize(src.Type(), src.Len())
+		c[ref] = dst
+		iter := src.MapRange()
+		for iter.Next() {
+			dst.SetMapIndex(iter.Key(), c.copy(iter.Value()))
+		}
+		return dst
+	case reflect.Slice:
+		if src.IsNil() {
+			return reflect.Zero(src.Type())
+		}
+		ref := copyRef{src.Pointer(), src.Type(), src.Len()}
+		if dst, ok := c[ref]; ok {
+			return dst
+		}
+		dst := reflect.MakeSlice(src.Type(), src.Len(), src.Len())
+		c[ref] = dst
+		for i := 0; i < src.Len(); i += 1 {
+			dst.Index(i).Set(c.copy(src.Index(i)))
+		}
+		return dst
+	case reflect.Array:
+		dst := reflect.New(src.Type()).Elem()
+		for i := 0; i < src.Len(); i += 1 {
+			dst.Index(i).Set(c.copy(src.Index(i)))
+		}
+		return dst
+	case reflect.Struct:
+		// unexported fields cannot be set, so they keep a shallow copy
+		dst := reflect.New(src.Type()).Elem()
+		dst.Set(src)
+		for i := 0; i < src.NumField(); i += 1 {
+			if dst.Field(i).CanSet() {
+				dst.Field(i).Set(c.copy(src.Field(i)))
+			}
+		}
+		return dst
+	default:
+		dst := reflect.New(src.Type()).Elem()
+		dst.Set(src)
+		return dst
+	}
+}
+
+// Converts structs into maps keyed by the struct tag (or field name), leaving
+// out fields without the tag when one is used. Pointers are replaced by the
+// values they point to, in the same way for structs and other values. Cyclic
+// values cannot be converted and return a DepthExceeded error.
+func (c *Compiled) structsToMaps(object reflect.Value) (interface{}, *Error) {
+	return c.convertStructs(object, map[copyRef]bool{})
+}
 
-	default:
-		if seg.isRecursive {
-			return temp, &Error{RecursiveMiss, fmt.Sprintf("path not found (%s)", fullKey)}
+// Converts the value for structsToMaps. visiting holds the references being
+// converted, which are only cyclic if they are reached again.
+func (c *Compiled) convertStructs(object reflect.Value, visiting map[copyRef]bool) (interface{}, *Error) {
+	if !object.IsValid() {
+		return nil, nil
+	}
+	if !containsStructsOrPointers(object.Type()) {
+		return object.Interface(), nil
+	}
+	switch object.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice:
+		if object.IsNil() {
+			return nil, nil
 		}
-		if c.strictPaths || seg.isWildcard {
-			return temp, &Error{NotFound, fmt.Sprintf("path not found (%s)", fullKey)}
+		ref := copyRef{object.Pointer(), object.Type(), 0}
+		if object.Kind() == reflect.Slice {
+			ref.len = object.Len()
 		}
-		if seg.isIndex {
-			new := reflect.ValueOf([]interface{}{})
-			parsed, err := parseIndexes(seg.indexes, 0, false)
+		if visiting[ref] {
+			return nil, &Error{Code: DepthExceeded, Msg: fmt.Sprintf("cannot convert cyclic value of type %s", object.Type().String())}
+		}
+		visiting[ref] = true
+		defer delete(visiting, ref)
+	}
+	switch object.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if object.IsNil() {
+			return nil, nil
+		}
+		return c.convertStructs(object.Elem(), visiting)
+	case reflect.Struct:
+		result := map[string]interface{}{}
+		for _, field := range c.structInfo(object.Type()).fields {
+			if !field.tagged {
+				continue
+			}
+			fieldValue := fieldByIndex(object, field.index, false)
+			if !fieldValue.IsValid() {
+				continue
+			}
+			value, err := c.convertStructs(fieldValue, visiting)
 			if err != nil {
-				return temp, err
+				return nil, err
 			}
-			new = fillSlice(new, parsed[len(parsed)-1])
-			for _, i := range parsed {
-				nextObject := new.Index(i)
-				temp, err = c.setNestedValues(nextObject, nil, path[1:], value, valueSet)
-				if err != nil {
-					return temp, err
-				}
-				if temp.IsValid() {
-					nextObject.Set(temp)
-				}
+			result[field.key] = value
+		}
+		return result, nil
+	case reflect.Slice, reflect.Array:
+		result := make([]interface{}, object.Len())
+		for i := 0; i < object.Len(); i += 1 {
+			value, err := c.convertStructs(object.Index(i), visiting)
+			if err != nil {
+				return nil, err
 			}
-			return new, err
-
-		} else {
-			new := reflect.ValueOf(map[string]interface{}{})
-			for _, k := range seg.keysRefl {
-				temp, err = c.setNestedValues(new.MapIndex(k), nil, path[1:], value, valueSet)
-				if err != nil {
-					return temp, err
-				}
-				if temp.IsValid() {
-					new.SetMapIndex(k, temp)
-				}
+			result[i] = value
+		}
+		return result, nil
+	case reflect.Map:
+		result := map[string]interface{}{}
+		iter := object.MapRange()
+		for iter.Next() {
+			value, err := c.convertStructs(iter.Value(), visiting)
+			if err != nil {
+				return nil, err
 			}
-			return new, err
+			result[keyString(iter.Key())] = value
 		}
+		return result, nil
+	default:
+		return object.Interface(), nil
 	}
+}
 
-	if derefenced {
-		return object, err
+// Checks whether values of the type can hold a struct or a pointer
+func containsStructsOrPointers(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Struct, reflect.Interface, reflect.Ptr:
+		return true
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return containsStructsOrPointers(t.Elem())
+	default:
+		return false
+	}
+}
+
+// Returns the inner value of sql.Null* style wrappers (a value field alongside
+// a Valid bool), or an invalid value if the wrapper is not valid
+func unwrapNullable(object reflect.Value) reflect.Value {
+	inner := object
+	for inner.Kind() == reflect.Ptr || inner.Kind() == reflect.Interface {
+		inner = inner.Elem()
+	}
+	if inner.Kind() != reflect.Struct || inner.NumField() != 2 {
+		return object
+	}
+	objType := inner.Type()
+	for i := 0; i < 2; i += 1 {
+		valid := objType.Field(i)
+		value := objType.Field(1 - i)
+		if valid.Name != "Valid" || valid.Type.Kind() != reflect.Bool || !value.IsExported() {
+			continue
+		}
+		if !inner.Field(i).Bool() {
+			return reflect.Value{}
+		}
+		return inner.Field(1 - i)
+	}
+	return object
+}
+
+// Checks whether a new value of the type can be created when setting values
+func (c *Compiled) canCreate(t reflect.Type) bool {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Map:
+		return !c.noCreateMaps
+	case reflect.Slice:
+		return !c.noCreateSlices
+	default:
+		return true
 	}
-	return objectRef, err
 }
 
 func initNewValue(t reflect.Type) reflect.Value {
@@ -308,7 +2594,7 @@ func initNewValue(t reflect.Type) reflect.Value {
 		ptr := reflect.New(t)
 		ptr.Elem().Set(reflect.MakeMap(t))
 		return ptr
-	case reflect.Slice, reflect.Array:
+	case reflect.Slice:
 		ptr := reflect.New(t)
 		ptr.Elem().Set(reflect.MakeSlice(t, 0, 0))
 		return ptr
@@ -318,30 +2604,112 @@ func initNewValue(t reflect.Type) reflect.Value {
 }
 
 func (c *Compiled) getNestedValues(object reflect.Value, path []segment) ([]interface{}, *Error) {
+	result, err := c.getSegment(object, path)
+	if c.trace != nil && err != nil && err.Path == "" && notFound(err) {
+		// only the node the error came from, before the segment is recorded
+		c.trace.Missing = append(c.trace.Missing, c.locationPath())
+	}
+	return result, c.atSegment(err, path)
+}
+
+// Gets the values at the first segment of path, and recursively at the rest
+func (c *Compiled) getSegment(object reflect.Value, path []segment) ([]interface{}, *Error) {
 	var err *Error
 	var temp []interface{}
 
+	if err = c.visit(); err != nil {
+		return temp, err
+	}
+	if c.walk != nil && c.walk.err != nil {
+		return temp, &Error{Code: walkStopped, Msg: "walk stopped"}
+	}
+
 	final := len(path) == 0
 	if final {
+		if c.unwrapNullable {
+			object = unwrapNullable(object)
+		}
+		if c.walk != nil {
+			var value interface{}
+			if object.IsValid() {
+				value = object.Interface()
+			}
+			return []interface{}{}, c.walkValue(value)
+		}
+		if c.trace != nil {
+			c.trace.Matched = append(c.trace.Matched, c.locationPath())
+		}
 		if object.IsValid() {
-			return []interface{}{object.Interface()}, nil
+			return []interface{}{c.match(object.Interface())}, nil
 		}
-		return []interface{}{nil}, nil
+		return []interface{}{c.match(nil)}, nil
 	}
 	seg := path[0]
 	fullKey := seg.raw
 
+	if seg.isParent {
+		return c.getParentValues(path)
+	}
+	if seg.function != "" && !seg.isLength {
+		return nil, &Error{Code: InvalidPath, Msg: fmt.Sprintf("%s() can only be used to get values (%s)", seg.function, fullKey)}
+	}
+
 	for object.Kind() == reflect.Ptr || object.Kind() == reflect.Interface {
 		object = object.Elem()
 	}
+	if c.unwrapNullable {
+		object = unwrapNullable(object)
+		for object.Kind() == reflect.Ptr || object.Kind() == reflect.Interface {
+			object = object.Elem()
+		}
+	}
 
 	result := []interface{}{}
 
+	if c.skipNilIntermediates && !c.strictPaths && !seg.isLength && isNil(object) {
+		// other matches are kept, and the path is only not found if nothing matched
+		return result, &Error{Code: RecursiveMiss, Msg: fmt.Sprintf("path not found (%s)", seg.raw)}
+	}
 	if !object.IsValid() {
-		return result, &Error{NotFound, fmt.Sprintf("path not found (%s)", seg.raw)}
+		if seg.isRecursive {
+			// recursive descent through null values matches nothing either
+			return result, &Error{Code: RecursiveMiss, Msg: fmt.Sprintf("path not found (%s)", seg.raw)}
+		}
+		return result, &Error{Code: NotFound, Msg: fmt.Sprintf("path not found (%s)", seg.raw)}
 	}
 
-	switch object.Kind() {
+	if c.parents != nil {
+		*c.parents = append(*c.parents, object)
+		defer func() { *c.parents = (*c.parents)[:len(*c.parents)-1] }()
+	}
+
+	if c.reached != nil && len(path) == 1 {
+		switch object.Kind() {
+		case reflect.Map, reflect.Struct, reflect.Slice, reflect.Array:
+			*c.reached = true
+		}
+	}
+
+	if seg.isLength {
+		switch object.Kind() {
+		case reflect.Map, reflect.Slice, reflect.Array:
+			return []interface{}{c.match(object.Len())}, nil
+		case reflect.String:
+			if seg.function == "length" {
+				return []interface{}{c.match(utf8.RuneCountInString(object.String()))}, nil
+			}
+		}
+		if seg.function == "length" {
+			return nil, &Error{Code: TypeMismatch, Msg: fmt.Sprintf("cannot get the length of type %s (%s)", object.Type().String(), fullKey)}
+		}
+		return nil, &Error{Code: NotFound, Msg: fmt.Sprintf("cannot get the length of type %s (%s)", object.Type().String(), fullKey)}
+	}
+
+	kind := object.Kind()
+	if (seg.isRecursive || seg.isWildcard) && isOpaque(object.Type()) {
+		kind = reflect.Invalid
+	}
+	switch kind {
 	case reflect.Map:
 		var keys []reflect.Value
 		keys, err = c.mapKeys(object, seg)
@@ -351,11 +2719,16 @@ func (c *Compiled) getNestedValues(object reflect.Value, path []segment) ([]inte
 		for _, k := range keys {
 			nextObject := object.MapIndex(k)
 			if !nextObject.IsValid() {
-				return temp, &Error{NotFound, fmt.Sprintf("key does not exist (%s)", seg.raw)}
+				return temp, &Error{Code: NotFound, Msg: fmt.Sprintf("key does not exist (%s)", seg.raw)}
 			}
+			if c.key != nil {
+				*c.key = keyString(k)
+			}
+			c.enterKey(k)
 			result, err = c.getCommon(nextObject, path, seg, result, func() bool {
-				return contains(seg.keysRefl, k)
+				return c.matchesKey(seg, k, nextObject)
 			})
+			c.leave()
 		}
 
 	case reflect.Struct:
@@ -366,53 +2739,140 @@ func (c *Compiled) getNestedValues(object reflect.Value, path []segment) ([]inte
 			return temp, err
 		}
 		for _, f := range fields {
-			nextObject := object.FieldByName(f)
+			nextObject := c.structField(object, f, false)
 			if !nextObject.IsValid() {
-				return temp, &Error{NotFound, fmt.Sprintf("field does not exist (%s)", seg.raw)}
+				return temp, &Error{Code: NotFound, Msg: fmt.Sprintf("field does not exist (%s)", seg.raw)}
+			}
+			if c.key != nil {
+				*c.key = c.fieldKey(object, f)
 			}
+			c.enterField(object, f)
 			result, err = c.getCommon(nextObject, path, seg, result, func() bool {
 				return slices.Contains(segFields, f)
 			})
+			c.leave()
 		}
 
 	case reflect.Slice, reflect.Array:
+		if c.pairsAsMap && !seg.isIndex {
+			if keys, values, ok := pairs(object); ok {
+				return c.getPairs(keys, values, path, seg)
+			}
+		}
 		var idxs []int
 		var segIdxs []int
-		idxs, segIdxs, err = c.sliceIndexes(object, seg, true)
+		idxs, segIdxs, err = c.sliceIndexes(object, seg, true, c.lenientIndices && !c.strictPaths)
 		if err != nil {
 			return temp, err
 		}
 		for _, i := range idxs {
 			nextObject := object.Index(i)
 			if !nextObject.IsValid() {
-				return temp, &Error{NotFound, fmt.Sprintf("index out of range (%d)", i)}
+				return temp, &Error{Code: NotFound, Msg: fmt.Sprintf("index out of range (%d)", i)}
 			}
+			if c.key != nil {
+				*c.key = strconv.Itoa(i)
+			}
+			c.enterIndex(i)
 			result, err = c.getCommon(nextObject, path, seg, result, func() bool {
 				return slices.Contains(segIdxs, i)
 			})
+			c.leave()
 		}
 
 	default:
 		if seg.isRecursive {
-			return nil, &Error{RecursiveMiss, fmt.Sprintf("path not found (%s)", fullKey)}
+			return nil, &Error{Code: RecursiveMiss, Msg: fmt.Sprintf("path not found (%s)", fullKey)}
 		}
-		return nil, &Error{NotFound, fmt.Sprintf("path not found (%s)", fullKey)}
+		return nil, &Error{Code: NotFound, Msg: fmt.Sprintf("path not found (%s)", fullKey)}
 	}
 
 	return result, err
 }
 
+// Gets values from a slice of [key, value] pairs in the same way as a map,
+// keeping the order of the pairs
+func (c *Compiled) getPairs(keys []reflect.Value, values []reflect.Value, path []segment, seg segment) ([]interface{}, *Error) {
+	result := []interface{}{}
+	var err *Error
+	if seg.isKey && !seg.isRecursive && seg.filter == nil && seg.keyFilter == nil {
+		for _, k := range seg.keysRefl {
+			i := slices.IndexFunc(keys, func(key reflect.Value) bool {
+				return key.String() == k.String()
+			})
+			if i == -1 {
+				return nil, &Error{Code: NotFound, Msg: fmt.Sprintf("key does not exist (%s)", seg.raw)}
+			}
+			if c.key != nil {
+				*c.key = k.String()
+			}
+			c.enterKey(k)
+			result, err = c.getCommon(values[i], path, seg, result, func() bool { return true })
+			c.leave()
+		}
+		return result, err
+	}
+	for i, k := range keys {
+		inSegment := func() bool {
+			return c.matchesKey(seg, k, values[i])
+		}
+		if !seg.isRecursive && !seg.isWildcard && !inSegment() {
+			continue
+		}
+		if c.key != nil {
+			*c.key = k.String()
+		}
+		c.enterKey(k)
+		result, err = c.getCommon(values[i], path, seg, result, inSegment)
+		c.leave()
+	}
+	return result, err
+}
+
+// Returns the keys and values of a slice where every element is a [key, value]
+// pair with a string key
+func pairs(object reflect.Value) ([]reflect.Value, []reflect.Value, bool) {
+	if object.Len() == 0 {
+		return nil, nil, false
+	}
+	keys := make([]reflect.Value, object.Len())
+	values := make([]reflect.Value, object.Len())
+	for i := 0; i < object.Len(); i += 1 {
+		pair := object.Index(i)
+		for pair.Kind() == reflect.Interface {
+			pair = pair.Elem()
+		}
+		if (pair.Kind() != reflect.Slice && pair.Kind() != reflect.Array) || pair.Len() != 2 {
+			return nil, nil, false
+		}
+		key := pair.Index(0)
+		for key.Kind() == reflect.Interface {
+			key = key.Elem()
+		}
+		if key.Kind() != reflect.String {
+			return nil, nil, false
+		}
+		keys[i] = key
+		values[i] = pair.Index(1)
+	}
+	return keys, values, true
+}
+
 func (c *Compiled) setCommon(
 	nextObject reflect.Value,
 	path []segment,
 	seg segment,
-	value interface{},
+	value func(reflect.Value) interface{},
 	valueSet *bool,
 	elemType reflect.Type,
 	setValue func(reflect.Value) *Error,
 	inSegment func() bool,
 ) *Error {
-	var err *Error
+	err := c.descend()
+	defer c.ascend()
+	if err != nil {
+		return err
+	}
 	var temp reflect.Value
 	nextPath := path[1:]
 	if seg.isRecursive && !inSegment() {
@@ -424,7 +2884,7 @@ func (c *Compiled) setCommon(
 	}
 	if temp.IsValid() {
 		if !temp.Type().AssignableTo(elemType) {
-			return &Error{NotFound, fmt.Sprintf("cannot assign type %s to type %s", temp.Type().String(), elemType.String())}
+			return &Error{Code: TypeMismatch, Msg: fmt.Sprintf("cannot assign type %s to type %s", temp.Type().String(), elemType.String())}
 		}
 		err := setValue(temp)
 		if err != nil {
@@ -442,93 +2902,466 @@ func (c *Compiled) getCommon(
 	inSegment func() bool,
 ) ([]interface{}, *Error) {
 	nextPaths := [][]segment{}
-	if seg.isRecursive {
+	matched := !seg.isRecursive || seg.isWildcard || inSegment()
+	if seg.isRecursive && !(matched && c.shallowestRecursive) {
 		nextPaths = append(nextPaths, path)
 	}
-	if !seg.isRecursive || seg.isWildcard || inSegment() {
-		nextPaths = append(nextPaths, path[1:])
+	if matched {
+		if c.walk != nil {
+			// walks visit a match before descending into it, so that the
+			// descent can be skipped
+			nextPaths = append([][]segment{path[1:]}, nextPaths...)
+		} else {
+			nextPaths = append(nextPaths, path[1:])
+		}
+	}
+	if c.depth != nil {
+		*c.depth += 1
+		defer func() { *c.depth -= 1 }()
+	}
+	err := c.descend()
+	defer c.ascend()
+	if err != nil {
+		return result, err
+	}
+	var key string
+	if c.key != nil {
+		key = *c.key
 	}
-	var err *Error
 	var temp []interface{}
 	for _, p := range nextPaths {
+		if c.key != nil {
+			// restore the key after descending further for recursive paths
+			*c.key = key
+		}
+		if c.walk != nil && len(c.walk.skipped) > 0 && len(p) == len(path) && c.walk.skipped[c.locationPath()] {
+			continue
+		}
 		temp, err = c.getNestedValues(nextObject, p)
 		if err != nil && err.Code != RecursiveMiss {
 			return result, err
 		}
-		if err == nil || temp != nil {
-			result = append(result, temp...)
+		if err == nil || temp != nil {
+			result = append(result, temp...)
+		}
+	}
+	return result, err
+}
+
+func (c *Compiled) mapKeys(object reflect.Value, seg segment) ([]reflect.Value, *Error) {
+	if seg.isWildcard || seg.isRecursive {
+		keys := object.MapKeys()
+		if c.sortKeys {
+			sortKeys(keys)
+		}
+		return keys, nil
+	} else if seg.isSortedKeys {
+		keys := object.MapKeys()
+		sortKeys(keys)
+		idxs, err := parseIndexes(seg.indexes, len(keys), true, false)
+		if err != nil {
+			return nil, err
+		}
+		selected := make([]reflect.Value, len(idxs))
+		for i, idx := range idxs {
+			selected[i] = keys[idx]
+		}
+		return selected, nil
+	} else {
+		keyType := object.Type().Key()
+		if seg.isIndex && !isIntKind(keyType.Kind()) {
+			return nil, &Error{Code: TypeMismatch, Msg: fmt.Sprintf("cannot access map with an index (%s)", seg.raw)}
+		}
+		if seg.keyFilter != nil || seg.filter != nil {
+			keys := []reflect.Value{}
+			for _, k := range object.MapKeys() {
+				if c.matchesKey(seg, k, object.MapIndex(k)) {
+					keys = append(keys, k)
+				}
+			}
+			if c.sortKeys {
+				sortKeys(keys)
+			}
+			return keys, nil
+		}
+		if keyType == stringType || keyType.Kind() == reflect.Interface {
+			return seg.keysRefl, nil
+		}
+		segKeys, err := segmentKeys(seg)
+		if err != nil {
+			return nil, err
+		}
+		keys := make([]reflect.Value, len(segKeys))
+		for i, k := range segKeys {
+			keys[i], err = convertKey(k, keyType)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return keys, nil
+	}
+}
+
+// Returns the keys of a segment as strings, including single indexes so they
+// can be used as integer map keys
+func segmentKeys(seg segment) ([]string, *Error) {
+	if !seg.isIndex {
+		return seg.keys, nil
+	}
+	keys := make([]string, len(seg.indexes))
+	for i, idx := range seg.indexes {
+		if idx.hasStart || idx.hasEnd {
+			return nil, &Error{Code: TypeMismatch, Msg: fmt.Sprintf("cannot access map with an index range (%s)", seg.raw)}
+		}
+		keys[i] = strconv.Itoa(idx.idx)
+	}
+	return keys, nil
+}
+
+// Converts a key from the path into the key type of a map, using
+// encoding.TextUnmarshaler if the type implements it
+func convertKey(key string, keyType reflect.Type) (reflect.Value, *Error) {
+	result := reflect.New(keyType)
+	if unmarshaler, ok := result.Interface().(encoding.TextUnmarshaler); ok {
+		if err := unmarshaler.UnmarshalText([]byte(key)); err != nil {
+			return result, &Error{Code: NotFound, Msg: fmt.Sprintf("cannot convert key (%s) to type %s: %s", key, keyType.String(), err)}
+		}
+		return result.Elem(), nil
+	}
+	result = result.Elem()
+	var err error
+	switch {
+	case keyType.Kind() == reflect.String:
+		result.SetString(key)
+	case result.CanInt():
+		var i int64
+		i, err = strconv.ParseInt(key, 10, keyType.Bits())
+		result.SetInt(i)
+	case result.CanUint():
+		var u uint64
+		u, err = strconv.ParseUint(key, 10, keyType.Bits())
+		result.SetUint(u)
+	case result.CanFloat():
+		var f float64
+		f, err = strconv.ParseFloat(key, keyType.Bits())
+		result.SetFloat(f)
+	case keyType.Kind() == reflect.Bool:
+		var b bool
+		b, err = strconv.ParseBool(key)
+		result.SetBool(b)
+	default:
+		return result, &Error{Code: NotFound, Msg: fmt.Sprintf("cannot use a path key with map key type %s (%s)", keyType.String(), key)}
+	}
+	if err != nil {
+		return result, &Error{Code: NotFound, Msg: fmt.Sprintf("cannot convert key (%s) to type %s", key, keyType.String())}
+	}
+	return result, nil
+}
+
+func isIntKind(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return true
+	}
+	return false
+}
+
+func (c *Compiled) sliceIndexes(object reflect.Value, seg segment, capLength bool, lenient bool) ([]int, []int, *Error) {
+	var err *Error
+	var idxs []int
+	var segIdxs []int
+	if seg.isWildcard || seg.isRecursive {
+		idxs = makeRange(0, object.Len()-1)
+	}
+	if !seg.isWildcard {
+		if !seg.isRecursive && seg.isKey {
+			return nil, nil, &Error{Code: TypeMismatch, Msg: fmt.Sprintf("cannot access array with a key (%s)", seg.raw)}
+		}
+		if seg.isSortedKeys {
+			return nil, nil, &Error{Code: TypeMismatch, Msg: fmt.Sprintf("cannot access array with sorted keys (%s)", seg.raw)}
+		}
+		if seg.filter != nil {
+			segIdxs = []int{}
+			for i := 0; i < object.Len(); i += 1 {
+				if c.matchesFilter(seg.filter, object.Index(i)) {
+					segIdxs = append(segIdxs, i)
+				}
+			}
+		} else if !capLength && c.appendsIndex(seg, object.Len()) {
+			segIdxs = []int{object.Len()}
+		} else {
+			if !capLength {
+				if err = c.checkGrowth(seg.indexes, object.Len()); err != nil {
+					return nil, nil, err
+				}
+			}
+			segIdxs, err = parseIndexes(seg.indexes, object.Len(), capLength, lenient)
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+		if !seg.isRecursive {
+			idxs = segIdxs
+		}
+	}
+	return idxs, segIdxs, nil
+}
+
+func (c *Compiled) structFields(object reflect.Value, seg segment) ([]string, []string, *Error) {
+	var fields []string
+	var segFields []string
+	var filtered []string
+	info := c.structInfo(object.Type())
+	if seg.isWildcard || seg.isRecursive || seg.keyFilter != nil || seg.filter != nil {
+		for _, field := range info.fields {
+			// fields promoted through nil embedded pointers can still be
+			// named, but are not matched
+			fieldValue := fieldByIndex(object, field.index, false)
+			if !fieldValue.IsValid() {
+				continue
+			}
+			fields = append(fields, field.name)
+			if seg.keyFilter != nil && field.tagged && seg.keyFilter.MatchString(field.key) {
+				filtered = append(filtered, field.name)
+			}
+			if seg.filter != nil && c.matchesFilter(seg.filter, fieldValue) {
+				filtered = append(filtered, field.name)
+			}
+		}
+	}
+	if !seg.isWildcard {
+		if seg.isIndex && !seg.isRecursive {
+			return nil, nil, &Error{Code: TypeMismatch, Msg: fmt.Sprintf("cannot access struct field with an index (%s)", seg.raw)}
+		}
+		segFields = seg.keys
+		if seg.keyFilter != nil || seg.filter != nil {
+			segFields = filtered
+		} else if c.structTagSet {
+			// segments are shared between calls, so map the tags onto a new slice
+			segFields = make([]string, len(seg.keys))
+			for i, k := range seg.keys {
+				segFields[i] = info.tags[k]
+			}
+		}
+		if !seg.isRecursive {
+			fields = segFields
+		}
+	}
+	return fields, segFields, nil
+}
+
+// The fields of a struct type resolved for a struct tag, which are cached as
+// resolving them through reflection is slow
+type structInfo struct {
+	// exported fields matched by wildcards and recursive descent, in order
+	fields []structFieldInfo
+	// field names by the name they are matched by, for tagged fields
+	tags map[string]string
+	// every field that can be named, including fields promoted from embedded
+	// structs and unexported fields, by field name
+	byName map[string]structFieldInfo
+}
+
+type structFieldInfo struct {
+	name  string
+	index []int
+	typ   reflect.Type
+	// name the field is matched by, which is the tag name when a struct tag
+	// is used
+	key string
+	// false for fields without the struct tag, which cannot be matched by name
+	tagged bool
+}
+
+type structInfoKey struct {
+	t            reflect.Type
+	structTag    string
+	structTagSet bool
+}
+
+// Resolved struct fields, keyed by the type and struct tag
+var structInfoCache sync.Map
+
+func (c *Compiled) structInfo(t reflect.Type) *structInfo {
+	key := structInfoKey{t: t, structTag: c.structTag, structTagSet: c.structTagSet}
+	if info, ok := structInfoCache.Load(key); ok {
+		return info.(*structInfo)
+	}
+	info := &structInfo{tags: map[string]string{}, byName: map[string]structFieldInfo{}}
+	// visible fields are the fields that FieldByName finds
+	for _, field := range reflect.VisibleFields(t) {
+		tag, tagged := c.tagName(field)
+		key := field.Name
+		if tag != "" {
+			key = tag
+		}
+		info.byName[field.Name] = structFieldInfo{
+			name:   field.Name,
+			index:  field.Index,
+			typ:    field.Type,
+			key:    key,
+			tagged: tagged || !c.structTagSet,
+		}
+	}
+	for _, field := range c.visibleFields(t) {
+		// unexported fields cannot be read through reflection, and are
+		// skipped in the same way as encoding/json
+		if !field.IsExported() {
+			continue
+		}
+		f := info.byName[field.Name]
+		if f.tagged && c.structTagSet {
+			info.tags[f.key] = f.name
 		}
+		info.fields = append(info.fields, f)
 	}
-	return result, err
+	cached, _ := structInfoCache.LoadOrStore(key, info)
+	return cached.(*structInfo)
 }
 
-func (c *Compiled) mapKeys(object reflect.Value, seg segment) ([]reflect.Value, *Error) {
-	if seg.isWildcard || seg.isRecursive {
-		return object.MapKeys(), nil
-	} else {
-		if seg.isIndex {
-			return nil, &Error{NotFound, fmt.Sprintf("cannot access map with an index (%s)", seg.raw)}
+// Returns the fields of a struct type, including fields promoted from embedded
+// structs. Embedded structs are flattened into their promoted fields, unless
+// they are named by the struct tag. Fields tagged "-" are skipped.
+func (c *Compiled) visibleFields(t reflect.Type) []reflect.StructField {
+	result := []reflect.StructField{}
+	var hidden [][]int
+	for _, field := range reflect.VisibleFields(t) {
+		if promotedFrom(field.Index, hidden) {
+			continue
+		}
+		if c.structTagSet && field.Tag.Get(c.structTag) == "-" {
+			hidden = append(hidden, field.Index)
+			continue
+		}
+		if field.Anonymous && isStructType(field.Type) {
+			if name, _ := c.tagName(field); name == "" {
+				continue
+			}
+			hidden = append(hidden, field.Index)
 		}
-		return seg.keysRefl, nil
+		result = append(result, field)
 	}
+	return result
 }
 
-func (c *Compiled) sliceIndexes(object reflect.Value, seg segment, capLength bool) ([]int, []int, *Error) {
-	var err *Error
-	var idxs []int
-	var segIdxs []int
-	if seg.isWildcard || seg.isRecursive {
-		idxs = makeRange(0, object.Len()-1)
+// Returns the name given to a struct field by the struct tag, without tag
+// options such as omitempty. The name is empty if the tag only has options.
+func (c *Compiled) tagName(field reflect.StructField) (string, bool) {
+	if !c.structTagSet {
+		return "", false
 	}
-	if !seg.isWildcard {
-		if !seg.isRecursive && seg.isKey {
-			return nil, nil, &Error{NotFound, fmt.Sprintf("cannot access array with a key (%s)", seg.raw)}
-		}
-		segIdxs, err = parseIndexes(seg.indexes, object.Len(), capLength)
-		if err != nil {
-			return nil, nil, err
+	val, ok := field.Tag.Lookup(c.structTag)
+	if !ok {
+		return "", false
+	}
+	return strings.Split(val, ",")[0], true
+}
+
+// Returns the name a struct field is matched by, which is the tag name when a
+// struct tag is used
+func (c *Compiled) fieldKey(object reflect.Value, name string) string {
+	if field, ok := c.structInfo(object.Type()).byName[name]; ok {
+		return field.key
+	}
+	return name
+}
+
+// Returns true if the field index is within one of the embedded struct indexes
+func promotedFrom(index []int, embedded [][]int) bool {
+	for _, e := range embedded {
+		if len(index) > len(e) && slices.Equal(index[:len(e)], e) {
+			return true
 		}
-		if !seg.isRecursive {
-			idxs = segIdxs
+	}
+	return false
+}
+
+// Returns true for types that encode themselves, such as time.Time, and byte
+// slices, which encode as base64 strings. Recursive descent and wildcards treat
+// these as values rather than descending into them.
+func isOpaque(t reflect.Type) bool {
+	if t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8 {
+		return true
+	}
+	for _, marshaler := range []reflect.Type{textMarshalerType, jsonMarshalerType} {
+		if t.Implements(marshaler) || reflect.PointerTo(t).Implements(marshaler) {
+			return true
 		}
 	}
-	return idxs, segIdxs, nil
+	return false
 }
 
-func (c *Compiled) structFields(object reflect.Value, seg segment) ([]string, []string, *Error) {
-	var fields []string
-	var segFields []string
-	tagMap := map[string]string{}
-	if seg.isWildcard || seg.isRecursive || c.structTagSet {
-		objType := object.Type()
-		for i := 0; i < object.NumField(); i += 1 {
-			field := objType.Field(i)
-			fields = append(fields, field.Name)
-			if c.structTagSet {
-				if val, ok := field.Tag.Lookup(c.structTag); ok {
-					tagMap[val] = field.Name
+func isStructType(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Struct
+}
+
+// Returns the struct field with the given name, including fields promoted
+// from embedded structs
+func (c *Compiled) structField(object reflect.Value, name string, create bool) reflect.Value {
+	field, ok := c.structInfo(object.Type()).byName[name]
+	if !ok {
+		return reflect.Value{}
+	}
+	return fieldByIndex(object, field.index, create)
+}
+
+// Returns the nested field at the index, or an invalid value if the field is
+// promoted through a nil embedded pointer. Nil embedded pointers are
+// allocated if create is true.
+func fieldByIndex(object reflect.Value, index []int, create bool) reflect.Value {
+	for i, x := range index {
+		if i > 0 && object.Kind() == reflect.Ptr {
+			if object.IsNil() {
+				if !create || !object.CanSet() {
+					return reflect.Value{}
 				}
+				object.Set(reflect.New(object.Type().Elem()))
 			}
+			object = object.Elem()
 		}
+		object = object.Field(x)
 	}
-	if !seg.isWildcard {
-		if seg.isIndex {
-			return nil, nil, &Error{NotFound, fmt.Sprintf("cannot access struct field with an index (%s)", seg.raw)}
-		}
-		segFields = seg.keys
-		if c.structTagSet {
-			for i, k := range segFields {
-				segFields[i] = tagMap[k]
-			}
-		}
-		if !seg.isRecursive {
-			fields = segFields
+	return object
+}
+
+func Compile(path string, options ...func(*Compiled)) (*Compiled, error) {
+	return compile(path, 0, options...)
+}
+
+// Compiles a path from a list of keys. Each key is used as is, so keys
+// containing '.', '[' or quotes do not need to be escaped.
+func CompileSegments(segments []string, options ...func(*Compiled)) (*Compiled, error) {
+	compiled := Compiled{
+		segments: []segment{},
+	}
+
+	for _, option := range options {
+		option(&compiled)
+	}
+
+	if len(segments) == 0 {
+		return nil, &Error{Code: InvalidPath, Msg: "empty path"}
+	}
+	for _, key := range segments {
+		seg := segment{
+			raw:     "[" + quoteKey(key) + "]",
+			indexes: []index{},
+			isKey:   true,
 		}
+		seg.addKeys([]string{key})
+		compiled.segments = append(compiled.segments, seg)
 	}
-	return fields, segFields, nil
+	compiled.raw = compiled.String()
+
+	return &compiled, nil
 }
 
-func Compile(path string, options ...func(*Compiled)) (*Compiled, error) {
+// Compiles the path, offsetting error positions by start
+func compile(path string, start int, options ...func(*Compiled)) (*Compiled, error) {
 	compiled := Compiled{
 		raw:      path,
 		segments: []segment{},
@@ -538,97 +3371,273 @@ func Compile(path string, options ...func(*Compiled)) (*Compiled, error) {
 		option(&compiled)
 	}
 
+	if branches, starts := splitUnion(path); len(branches) > 1 {
+		for i, branch := range branches {
+			b, err := compile(branch, start+starts[i], options...)
+			if err != nil {
+				return nil, err
+			}
+			compiled.union = append(compiled.union, b)
+			compiled.hasFilter = compiled.hasFilter || b.hasFilter
+		}
+		compiled.hasMulti = true
+		return &compiled, nil
+	}
+
 	var key string
 	var keyEnd bool
 	var inBracket bool
+	var inFilter bool
+	var filterDepth int
 	var inQuote bool
 	var quoteChar rune
+	var trailingSpace bool
+	var keyStart int
+	var bracketStart int
+	var quoteStart int
+	offset := start
+	positions := []int{}
+
+	if compiled.tolerantWhitespace {
+		offset += len(path) - len(strings.TrimLeftFunc(path, unicode.IsSpace))
+		path = strings.TrimSpace(path)
+	}
 
 	if path == "" {
-		return &compiled, &Error{InvalidPath, "empty path"}
+		return &compiled, invalidPathAt("empty path", offset)
 	}
 
-	path = strings.TrimPrefix(path, "$")
+	if root := compiled.root(); root != "" && strings.HasPrefix(path, root) {
+		// the root token must not be the start of a longer key
+		rest := path[len(root):]
+		if rest == "" || strings.ContainsAny(rest[:1], ".[^") || unicode.IsSpace(rune(rest[0])) {
+			path = rest
+			offset += len(root)
+		}
+	}
+	if compiled.tolerantWhitespace {
+		trimmed := strings.TrimLeftFunc(path, unicode.IsSpace)
+		offset += len(path) - len(trimmed)
+		path = trimmed
+	}
 	if path == "." {
 		return &compiled, nil
 	}
 
+	// segments are large, so they are allocated once for the number of
+	// separators, which is at least the number of segments in most paths
+	capacity := strings.Count(path, ".") + strings.Count(path, "[") + 1
+	compiled.segments = make([]segment, 0, capacity)
+	positions = make([]int, 0, capacity)
+
 	for i, c := range path {
-		if inQuote && c == quoteChar && lastChar(key) != "\\" {
+		if inQuote && c == quoteChar && !isEscaped(key) {
 			inQuote = false
 
 		} else if !inQuote && (c == '\'' || c == '"') {
 			if !inBracket {
-				return nil, &Error{InvalidPath, "cannot use quotes outside of brackets"}
+				return nil, invalidPathAt("cannot use quotes outside of brackets", offset+i)
 			}
 			inQuote = true
 			quoteChar = c
+			quoteStart = i
+
+		} else if !inQuote && inBracket && c == '/' && isPatternStart(key) {
+			// treat a key filter pattern as quoted
+			inQuote = true
+			quoteChar = c
+			quoteStart = i
+		}
+
+		if c == '?' && inBracket && !inQuote && strings.TrimLeft(strings.TrimSpace(key), ".") == "[" {
+			inFilter = true
 		}
 
-		if c == '.' && !inQuote && key != "" && key != "." {
+		// escaped dots are part of the key, e.g. a\.b
+		if c == '.' && !inQuote && !inBracket && key != "" && key != "." && !isEscaped(key) {
 			if i == len(path)-1 {
-				return nil, &Error{InvalidPath, "path cannot end with '.' separator"}
+				return nil, invalidPathAt("path cannot end with '.' separator", offset+i)
 			}
 			keyEnd = true
 		}
 
-		if c == '[' && !inQuote {
+		if c == '^' && !inQuote && !inBracket && strings.Trim(key, ".") != "" && isParentSelector(path[i:]) {
+			// parent selectors are separate segments, e.g. key^^
+			keyEnd = true
+		}
+
+		if inFilter && !inQuote && (c == '[' || (c == ']' && filterDepth > 0)) {
+			// brackets nested within a filter
+			if c == '[' {
+				filterDepth += 1
+			} else {
+				filterDepth -= 1
+			}
+
+		} else if c == '[' && !inQuote {
 			if inBracket {
-				return nil, &Error{InvalidPath, "missing closing bracket"}
+				return nil, invalidPathAt("missing closing bracket", offset+bracketStart)
 			}
 			inBracket = true
+			bracketStart = i
 			if i != 0 && key != "." && key != ".." {
 				keyEnd = true
 			}
-		}
 
-		if c == ']' && !inQuote {
+		} else if c == ']' && !inQuote {
 			if !inBracket {
-				return nil, &Error{InvalidPath, "missing opening bracket"}
+				return nil, invalidPathAt("missing opening bracket", offset+i)
 			}
 			inBracket = false
+			inFilter = false
 		}
 
 		if unicode.IsSpace(c) && !inQuote && !inBracket {
-			return nil, &Error{InvalidPath, "cannot use whitespace characters outside quotes and brackets"}
+			if !compiled.tolerantWhitespace {
+				return nil, invalidPathAt("cannot use whitespace characters outside quotes and brackets", offset+i)
+			}
+			if strings.Trim(key, ".") != "" {
+				trailingSpace = true
+			}
+			continue
+		}
+
+		if trailingSpace && c != '.' && c != '[' {
+			return nil, invalidPathAt("cannot use whitespace characters within keys", offset+i)
 		}
+		trailingSpace = false
 
 		if keyEnd {
-			segment, err := parseKey(key)
+			segment, err := compiled.parseKey(key)
 			if err != nil {
-				return nil, err
+				return nil, withPosition(err, offset+keyStart)
 			}
 			compiled.segments = append(compiled.segments, segment)
 			compiled.hasMulti = compiled.hasMulti || segment.isMulti
+			compiled.hasFilter = compiled.hasFilter || segment.filter != nil
+			positions = append(positions, offset+keyStart)
 
 			key = ""
 			keyEnd = false
 		}
 
-		key += string(c)
+		if key == "" {
+			keyStart = i
+		}
+		key = appendRune(path, key, keyStart, i, c)
 	}
 
 	if key != "" {
-		segment, err := parseKey(key)
+		segment, err := compiled.parseKey(key)
 		if err != nil {
-			return nil, err
+			return nil, withPosition(err, offset+keyStart)
 		}
 		compiled.segments = append(compiled.segments, segment)
 		compiled.hasMulti = compiled.hasMulti || segment.isMulti
+		compiled.hasFilter = compiled.hasFilter || segment.filter != nil
+		positions = append(positions, offset+keyStart)
 	}
 
 	if inBracket {
-		return nil, &Error{InvalidPath, "missing closing bracket"}
+		return nil, invalidPathAt("missing closing bracket", offset+bracketStart)
 	}
 	if inQuote {
-		return nil, &Error{InvalidPath, "missing closing quote"}
+		return nil, invalidPathAt("missing closing quote", offset+quoteStart)
+	}
+
+	// merge sorted key selectors with the index segment that follows them
+	for i := 0; i < len(compiled.segments); i += 1 {
+		if !compiled.segments[i].isSortedKeys {
+			continue
+		}
+		if i == len(compiled.segments)-1 || !compiled.segments[i+1].isIndex {
+			return nil, invalidPathAt("sorted keys selector must be followed by an index or range", positions[i])
+		}
+		next := compiled.segments[i+1]
+		compiled.segments[i].raw += next.raw
+		compiled.segments[i].indexes = next.indexes
+		compiled.segments[i].isIndex = true
+		compiled.segments[i].isMulti = next.isMulti
+		compiled.segments = slices.Delete(compiled.segments, i+1, i+2)
+		positions = slices.Delete(positions, i+1, i+2)
+	}
+
+	for i, segment := range compiled.segments {
+		if segment.function != "" && i != len(compiled.segments)-1 {
+			return nil, invalidPathAt(fmt.Sprintf("%s() must be the last path segment", segment.function), positions[i])
+		}
+		if segment.isLength && i != len(compiled.segments)-1 {
+			return nil, invalidPathAt("length selector must be the last path segment", positions[i])
+		}
+	}
+	if compiled.aggregate() != "" {
+		// the aggregate is a single value, however many values the path matches
+		compiled.hasMulti = false
+	}
+
+	for _, segment := range compiled.segments {
+		if segment.filter != nil {
+			segment.filter.inherit(&compiled)
+		}
 	}
 
 	return &compiled, nil
 }
 
+// Checks the path syntax without compiling it for use. Errors include the
+// byte offset in the path where the syntax is invalid.
+func ValidatePath(path string, options ...func(*Compiled)) error {
+	_, err := Compile(path, options...)
+	return err
+}
+
+// Compiles the path and returns it in normalized bracket notation, so that
+// equivalent paths, such as $.a.b and a['b'], return the same string
+func Normalize(path string, options ...func(*Compiled)) (string, error) {
+	c, err := Compile(path, options...)
+	if err != nil {
+		return "", err
+	}
+	return c.String(), nil
+}
+
+func invalidPathAt(msg string, pos int) *Error {
+	return &Error{Code: InvalidPath, Msg: fmt.Sprintf("%s at position %d", msg, pos)}
+}
+
+// Returns true if a / read after key starts a regular expression, either in a
+// key filter (=~ /) or a key pattern ([~/)
+func isPatternStart(key string) bool {
+	key = strings.TrimRightFunc(key, unicode.IsSpace)
+	if strings.HasSuffix(key, "=~") {
+		return true
+	}
+	key = strings.TrimPrefix(strings.TrimLeft(key, "."), "[")
+	return strings.TrimSpace(key) == "~"
+}
+
+// Appends the position to errors returned while parsing path keys
+func withPosition(err error, pos int) error {
+	if e, ok := err.(*Error); ok {
+		result := *e
+		result.Msg = fmt.Sprintf("%s at position %d", e.Msg, pos)
+		return &result
+	}
+	return err
+}
+
+// Prefixes errors with the path they were returned for
+func withPath(err error, path string) error {
+	if e, ok := err.(*Error); ok {
+		result := *e
+		result.Msg = fmt.Sprintf("%s: %s", path, e.Msg)
+		return &result
+	}
+	return err
+}
+
 // Parses path keys
-func parseKey(fullKey string) (segment, error) {
+func (c *Compiled) parseKey(fullKey string) (segment, error) {
 	var err error
 	result := segment{
 		raw:      fullKey,
@@ -640,7 +3649,7 @@ func parseKey(fullKey string) (segment, error) {
 	fullKey = strings.TrimPrefix(fullKey, ".")
 
 	if fullKey == "" {
-		return result, &Error{InvalidPath, "empty path segment"}
+		return result, &Error{Code: InvalidPath, Msg: "empty path segment"}
 	}
 
 	// Is a wildcard
@@ -656,31 +3665,81 @@ func parseKey(fullKey string) (segment, error) {
 		result.isMulti = true
 		fullKey = strings.TrimPrefix(fullKey, ".")
 		if fullKey == "" || string(fullKey[0]) == "." {
-			return result, &Error{InvalidPath, "invalid recursive path"}
+			return result, &Error{Code: InvalidPath, Msg: "invalid recursive path"}
+		}
+		// Is a recursive wildcard
+		if fullKey == "*" {
+			result.isWildcard = true
+			return result, nil
+		}
+	}
+
+	// Is a parent selector
+	if fullKey == "^" {
+		if result.isRecursive {
+			return result, &Error{Code: InvalidPath, Msg: "cannot use a parent selector with recursive descent"}
+		}
+		result.isParent = true
+		return result, nil
+	}
+
+	// Is a function ending the path
+	if name, ok := strings.CutSuffix(fullKey, "()"); ok && (name == "length" || slices.Contains(aggregateFuncs, name)) {
+		if result.isRecursive {
+			return result, &Error{Code: InvalidPath, Msg: fmt.Sprintf("cannot use %s with recursive descent", fullKey)}
+		}
+		result.function = name
+		result.isLength = result.function == "length"
+		return result, nil
+	}
+
+	// Is a sorted keys selector, which takes its indexes from the next segment
+	if fullKey == "sortedKeys()" {
+		if result.isRecursive {
+			return result, &Error{Code: InvalidPath, Msg: "cannot use a sorted keys selector with recursive descent"}
 		}
+		result.isSortedKeys = true
+		return result, nil
 	}
 
 	// Check for square brackets
 	if string(fullKey[0]) != "[" || string(fullKey[len(fullKey)-1]) != "]" {
+		parts := splitDotKey(fullKey)
+		if len(parts) > 1 {
+			return parseGlobKey(result, parts)
+		}
 		result.isKey = true
-		result.addKeys([]string{fullKey})
+		result.addKeys(parts)
 		return result, nil
 	}
 
 	key := strings.TrimSpace(fullKey[1 : len(fullKey)-1])
 
 	if key == "" {
-		return result, &Error{InvalidPath, "empty path segment"}
+		return result, &Error{Code: InvalidPath, Msg: "empty path segment"}
+	}
+
+	// Check for a filter
+	if strings.HasPrefix(key, "?") {
+		return parseFilter(result, key)
+	}
+
+	// Check for a key pattern
+	if strings.HasPrefix(key, "~") {
+		return parseKeyPattern(result, key)
 	}
 
 	keys := []string{}
+	// the keys that are not indexes or ranges
+	names := []string{}
 
 	// Split the key into it's parts
 	var segment string
+	var segmentStart int
 	var readSegment bool
 	var quoted bool
 	var quoteChar rune
-	for _, c := range key {
+	for i, c := range key {
 		if readSegment {
 			if !quoted {
 				if unicode.IsSpace(c) {
@@ -693,11 +3752,10 @@ func parseKey(fullKey string) (segment, error) {
 					continue
 				}
 			}
-			if quoted && c == quoteChar && lastChar(segment) != "\\" {
-				segment = strings.ReplaceAll(segment, "\\"+string(quoteChar), string(quoteChar))
+			if quoted && c == quoteChar && !isEscaped(segment) {
 				quoted = false
 			}
-			segment += string(c)
+			segment = appendRune(key, segment, segmentStart, i, c)
 
 		} else if !unicode.IsSpace(c) {
 			readSegment = true
@@ -705,13 +3763,14 @@ func parseKey(fullKey string) (segment, error) {
 				quoteChar = c
 				quoted = true
 			}
-			segment += string(c)
+			segmentStart = i
+			segment = appendRune(key, segment, segmentStart, i, c)
 		}
 	}
 
 	if readSegment {
 		if quoted {
-			return result, &Error{InvalidPath, "missing closing quote"}
+			return result, &Error{Code: InvalidPath, Msg: "missing closing quote"}
 		}
 		keys = append(keys, segment)
 	}
@@ -720,23 +3779,45 @@ func parseKey(fullKey string) (segment, error) {
 		// Check for a wildcard
 		if k == "*" {
 			if len(keys) > 1 {
-				return result, &Error{InvalidPath, "cannot use a wildcard with a multi-select"}
+				return result, &Error{Code: InvalidPath, Msg: "cannot use a wildcard with a multi-select"}
 			}
 			result.isWildcard = true
 			result.isMulti = true
 			return result, nil
 		}
 
+		// Check for a length selector
+		if k == "#" {
+			if len(keys) > 1 {
+				return result, &Error{Code: InvalidPath, Msg: "cannot use a length selector with a multi-select"}
+			}
+			if result.isRecursive {
+				return result, &Error{Code: InvalidPath, Msg: "cannot use a length selector with recursive descent"}
+			}
+			result.isLength = true
+			return result, nil
+		}
+
 		// If quoted string (treat as a map key)
 		if len(k) >= 2 && string(k[0]) == "\"" && string(k[len(k)-1]) == "\"" {
-			keys[i] = k[1 : len(k)-1]
+			keys[i] = unescapeKey(k[1 : len(k)-1])
+			names = append(names, keys[i])
 			continue
 		}
 		if len(k) >= 2 && string(k[0]) == "'" && string(k[len(k)-1]) == "'" {
-			keys[i] = k[1 : len(k)-1]
+			keys[i] = unescapeKey(k[1 : len(k)-1])
+			names = append(names, keys[i])
 			continue
 		}
 
+		// Check for a glob key
+		if strings.Contains(k, "*") {
+			if len(keys) > 1 {
+				return result, &Error{Code: InvalidPath, Msg: "cannot use a glob key with a multi-select"}
+			}
+			return parseGlobKey(result, strings.Split(k, "*"))
+		}
+
 		// Check if the key is an index
 		idx, err := strconv.Atoi(k)
 		if err == nil {
@@ -745,13 +3826,16 @@ func parseKey(fullKey string) (segment, error) {
 		}
 
 		// Check if the key is a range
-		rangeKey := rangeRegex.FindStringSubmatch(k)
+		var rangeKey []string
+		if strings.Contains(k, ":") {
+			rangeKey = rangeRegex.FindStringSubmatch(k)
+		}
 		if len(rangeKey) > 0 {
 			idx := index{}
 			if rangeKey[1] != "" {
 				start, err := strconv.Atoi(rangeKey[1])
 				if err != nil {
-					return result, &Error{InvalidPath, "invalid range"}
+					return result, &Error{Code: InvalidPath, Msg: "invalid range"}
 				}
 				idx.start = start
 				idx.hasStart = true
@@ -759,17 +3843,20 @@ func parseKey(fullKey string) (segment, error) {
 			if rangeKey[2] != "" {
 				end, err := strconv.Atoi(rangeKey[2])
 				if err != nil {
-					return result, &Error{InvalidPath, "invalid range"}
+					return result, &Error{Code: InvalidPath, Msg: "invalid range"}
 				}
 				idx.end = end
 				idx.hasEnd = true
 			}
 			result.indexes = append(result.indexes, idx)
 			result.isMulti = true
-			if idx.hasStart && idx.hasEnd && idx.start == idx.end {
-				return result, &Error{InvalidPath, fmt.Sprintf("invalid index range [%d:%d]", idx.start, idx.end)}
-			}
+			continue
 		}
+
+		if c.strictQuotes {
+			return result, &Error{Code: InvalidPath, Msg: fmt.Sprintf("keys within brackets must be quoted (%s)", k)}
+		}
+		names = append(names, k)
 	}
 
 	result.isMulti = result.isMulti || len(keys) > 1
@@ -783,20 +3870,51 @@ func parseKey(fullKey string) (segment, error) {
 	result.isIndex = true
 
 	if len(result.indexes) != len(keys) {
-		return result, &Error{InvalidPath, "cannot specify both array indexes and map keys in a multi-select"}
+		if !result.isRecursive {
+			return result, &Error{Code: InvalidPath, Msg: "cannot specify both array indexes and map keys in a multi-select"}
+		}
+		// recursive descent matches the keys in maps and structs, and the
+		// indexes in arrays
+		result.isKey = true
+		result.addKeys(names)
 	}
 
 	return result, err
 }
 
+func (c *Compiled) matchesKey(seg segment, key reflect.Value, value reflect.Value) bool {
+	if seg.filter != nil {
+		return c.matchesFilter(seg.filter, value)
+	}
+	if seg.keyFilter != nil {
+		return seg.keyFilter.MatchString(keyString(key))
+	}
+	if contains(seg.keysRefl, key) {
+		return true
+	}
+	if key.Kind() == reflect.String {
+		return false
+	}
+	// compare keys of other types, such as integers, in their path form
+	segKeys, err := segmentKeys(seg)
+	return err == nil && slices.Contains(segKeys, keyString(key))
+}
+
 func (s *segment) addKeys(keys []string) {
 	s.keys = keys
+	s.keysRefl = make([]reflect.Value, 0, len(keys))
 	for _, k := range keys {
 		s.keysRefl = append(s.keysRefl, reflect.ValueOf(k))
 	}
 }
 
-func parseIndexes(indexes []index, length int, capLength bool) ([]int, *Error) {
+// Resolves indexes against a slice of the given length. Negative indexes,
+// including the start of a range such as [-2:], count back from the current
+// end of the slice, even when setting values would grow it, and are out of
+// range if they resolve below zero. The indexes are returned sorted without
+// duplicates, and an out of range index fails all of them, unless lenient, in
+// which case out of range indexes are dropped and ranges are cut to fit.
+func parseIndexes(indexes []index, length int, capLength bool, lenient bool) ([]int, *Error) {
 	var err *Error
 	temp := map[int]struct{}{}
 	parsed := []int{}
@@ -804,23 +3922,42 @@ func parseIndexes(indexes []index, length int, capLength bool) ([]int, *Error) {
 		if !idx.hasStart && !idx.hasEnd {
 			i, err := wrapIndex(idx.idx, length, capLength)
 			if err != nil {
+				if lenient {
+					continue
+				}
 				return nil, err
 			}
 			temp[i] = struct{}{}
 			continue
 		}
+		if idx.hasStart && idx.hasEnd && idx.start == idx.end {
+			// an empty range, such as [1:1]
+			continue
+		}
 		var start int
 		var end int
 		if idx.hasStart {
 			start, err = wrapIndex(idx.start, length, capLength)
 			if err != nil {
-				return nil, err
+				if !lenient {
+					return nil, &Error{Code: NotFound, Msg: fmt.Sprintf("index out of range (%s)", idx)}
+				}
+				if start >= length {
+					continue
+				}
+				start = 0
 			}
 		}
 		if idx.hasEnd {
 			end, err = wrapIndex(idx.end-1, length, capLength)
 			if err != nil {
-				return nil, err
+				if !lenient {
+					return nil, &Error{Code: NotFound, Msg: fmt.Sprintf("index out of range (%s)", idx)}
+				}
+				if end < 0 {
+					continue
+				}
+				end = length - 1
 			}
 		} else {
 			end = length - 1
@@ -830,7 +3967,7 @@ func parseIndexes(indexes []index, length int, capLength bool) ([]int, *Error) {
 			continue
 		}
 		if start > end {
-			return parsed, &Error{NotFound, fmt.Sprintf("indexes out of range [%d:%d]", idx.start, idx.end)}
+			return parsed, &Error{Code: NotFound, Msg: fmt.Sprintf("indexes out of range [%d:%d]", idx.start, idx.end)}
 		}
 		for _, i := range makeRange(start, end) {
 			temp[i] = struct{}{}
@@ -845,13 +3982,49 @@ func parseIndexes(indexes []index, length int, capLength bool) ([]int, *Error) {
 	return parsed, nil
 }
 
+// Returns an error if setting the indexes would add more elements to a slice
+// of the given length than allowed by MaxGrowth
+func (c *Compiled) checkGrowth(indexes []index, length int) *Error {
+	limit := c.maxGrowth
+	if limit <= 0 {
+		limit = defaultMaxGrowth
+	}
+	last := -1
+	for _, idx := range indexes {
+		end := -1
+		if !idx.hasStart && !idx.hasEnd {
+			end = idx.idx
+		} else if idx.hasEnd {
+			end = idx.end - 1
+		}
+		if end > last {
+			last = end
+		}
+	}
+	if last+1-length > limit {
+		return &Error{Code: LimitExceeded, Msg: fmt.Sprintf("setting index %d would add more than %d elements to the array", last, limit)}
+	}
+	return nil
+}
+
+// appendsIndex reports whether setting the segment on an array of the given
+// length appends to it, which is the case for a single out of range index
+// with AppendOnGrow
+func (c *Compiled) appendsIndex(seg segment, length int) bool {
+	if !c.appendOnGrow || seg.isRecursive || len(seg.indexes) != 1 {
+		return false
+	}
+	idx := seg.indexes[0]
+	return !idx.hasStart && !idx.hasEnd && idx.idx >= length
+}
+
 func wrapIndex(idx, length int, capLength bool) (int, *Error) {
 	tmp := idx
 	if tmp < 0 {
 		tmp = length + tmp
 	}
 	if tmp < 0 || (capLength && tmp >= length) {
-		return tmp, &Error{NotFound, fmt.Sprintf("index out of range (%d)", idx)}
+		return tmp, &Error{Code: NotFound, Msg: fmt.Sprintf("index out of range (%d)", idx)}
 	}
 	return tmp, nil
 }
@@ -878,16 +4051,175 @@ func fillSlice(slice reflect.Value, lastIndex int) reflect.Value {
 	return new
 }
 
-func lastChar(val string) string {
-	if len(val) == 0 {
-		return ""
+func sortKeys(keys []reflect.Value) {
+	sort.SliceStable(keys, func(i, j int) bool {
+		a, b := keys[i], keys[j]
+		switch {
+		case a.CanInt() && b.CanInt():
+			return a.Int() < b.Int()
+		case a.CanUint() && b.CanUint():
+			return a.Uint() < b.Uint()
+		case a.CanFloat() && b.CanFloat():
+			return a.Float() < b.Float()
+		}
+		return keyString(a) < keyString(b)
+	})
+}
+
+func keyString(key reflect.Value) string {
+	if key.Kind() == reflect.String {
+		return key.String()
+	}
+	return fmt.Sprint(key.Interface())
+}
+
+// Returns true for missing values and nil maps and slices
+func isNil(object reflect.Value) bool {
+	if !object.IsValid() {
+		return true
+	}
+	switch object.Kind() {
+	case reflect.Map, reflect.Slice:
+		return object.IsNil()
+	}
+	return false
+}
+
+// Appends the rune c at offset i of src to val, which was started at offset
+// start. While nothing has been skipped since the start, val is a slice of src
+// instead of a copy, which saves an allocation for each rune.
+func appendRune(src string, val string, start int, i int, c rune) string {
+	if c != utf8.RuneError && len(val) == i-start {
+		return src[start : i+utf8.RuneLen(c)]
+	}
+	return val + string(c)
+}
+
+// Returns true if val ends in an unescaped backslash, which escapes the
+// character that follows it
+func isEscaped(val string) bool {
+	var n int
+	for i := len(val) - 1; i >= 0 && val[i] == '\\'; i -= 1 {
+		n += 1
 	}
-	return string(val[len(val)-1])
+	return n%2 == 1
 }
 
+// Returns true if the run of '^' at the start of val ends the segment, in which
+// case it is a parent selector rather than part of a key such as a^b
+func isParentSelector(val string) bool {
+	rest := strings.TrimLeft(val, "^")
+	if rest == "" {
+		return true
+	}
+	r, _ := utf8.DecodeRuneInString(rest)
+	return r == '.' || r == '[' || unicode.IsSpace(r)
+}
+
+// Splits a dot notation key on the '*' glob wildcards, and removes the
+// backslashes escaping dots and stars, so a\.b is the key a.b and a\*b is the
+// key a*b. Other backslashes are kept as written.
+func splitDotKey(key string) []string {
+	if !strings.ContainsAny(key, "\\*") {
+		return []string{key}
+	}
+	parts := []string{}
+	var b strings.Builder
+	for i := 0; i < len(key); i += 1 {
+		escaped := isEscaped(key[:i])
+		if key[i] == '\\' && i < len(key)-1 && (key[i+1] == '.' || key[i+1] == '*') && !escaped {
+			continue
+		}
+		if key[i] == '*' && !escaped {
+			parts = append(parts, b.String())
+			b.Reset()
+			continue
+		}
+		b.WriteByte(key[i])
+	}
+	return append(parts, b.String())
+}
+
+// Decodes the JSON string escapes in a quoted key, as well as escaped single
+// quotes. Unknown escapes are kept as written.
+func unescapeKey(key string) string {
+	if !strings.Contains(key, "\\") {
+		return key
+	}
+	var b strings.Builder
+	for i := 0; i < len(key); i += 1 {
+		if key[i] != '\\' || i == len(key)-1 {
+			b.WriteByte(key[i])
+			continue
+		}
+		i += 1
+		switch key[i] {
+		case 'n':
+			b.WriteByte('\n')
+		case 't':
+			b.WriteByte('\t')
+		case 'r':
+			b.WriteByte('\r')
+		case 'b':
+			b.WriteByte('\b')
+		case 'f':
+			b.WriteByte('\f')
+		case '\\', '/', '"', '\'':
+			b.WriteByte(key[i])
+		case 'u':
+			r, ok := hexRune(key[i+1:])
+			if !ok {
+				b.WriteString("\\u")
+				break
+			}
+			i += 4
+			// characters outside the basic plane are written as surrogate pairs
+			if utf16.IsSurrogate(r) && strings.HasPrefix(key[i+1:], "\\u") {
+				if low, ok := hexRune(key[i+3:]); ok {
+					if pair := utf16.DecodeRune(r, low); pair != unicode.ReplacementChar {
+						r = pair
+						i += 6
+					}
+				}
+			}
+			b.WriteRune(r)
+		default:
+			b.WriteByte('\\')
+			b.WriteByte(key[i])
+		}
+	}
+	return b.String()
+}
+
+// Parses the four hex digits of a \u escape
+func hexRune(val string) (rune, bool) {
+	if len(val) < 4 {
+		return 0, false
+	}
+	n, err := strconv.ParseUint(val[:4], 16, 32)
+	if err != nil {
+		return 0, false
+	}
+	return rune(n), true
+}
+
+// Returns true if the slice holds a value equal to val. Values are only equal
+// if they have the same type, or are both strings.
 func contains(slice []reflect.Value, val reflect.Value) bool {
+	for val.Kind() == reflect.Interface && !val.IsNil() {
+		val = val.Elem()
+	}
+	if !val.IsValid() || !val.Comparable() {
+		return false
+	}
 	for _, v := range slice {
-		if v.Comparable() && v.Interface() == val.Interface() {
+		if v.Kind() == reflect.String && val.Kind() == reflect.String {
+			if v.String() == val.String() {
+				return true
+			}
+			continue
+		}
+		if v.Type() == val.Type() && v.Comparable() && v.Equal(val) {
 			return true
 		}
 	}
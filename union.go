@@ -0,0 +1,146 @@
+package jsonpath
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"unicode"
+)
+
+// Splits a path on '|' separators found outside of quotes and brackets,
+// returning the trimmed branches and their offsets within the path. Paths
+// without a separator return no branches.
+func splitUnion(path string) ([]string, []int) {
+	var branches []string
+	var starts []int
+	var inQuote bool
+	var quoteChar byte
+	var depth int
+	var start int
+
+	add := func(end int) {
+		branch := path[start:end]
+		trimmed := strings.TrimLeftFunc(branch, unicode.IsSpace)
+		starts = append(starts, start+len(branch)-len(trimmed))
+		branches = append(branches, strings.TrimRightFunc(trimmed, unicode.IsSpace))
+	}
+
+	for i := 0; i < len(path); i += 1 {
+		c := path[i]
+		if inQuote {
+			if c == quoteChar && path[i-1] != '\\' {
+				inQuote = false
+			}
+			continue
+		}
+		switch c {
+		case '\'', '"':
+			inQuote = depth > 0
+			quoteChar = c
+		case '[':
+			depth += 1
+		case ']':
+			depth -= 1
+		case '|':
+			if depth == 0 {
+				add(i)
+				start = i + 1
+			}
+		}
+	}
+	if branches == nil {
+		return nil, nil
+	}
+	add(len(path))
+	return branches, starts
+}
+
+// Returns the branches of a union path, or the path itself when it is not a
+// union. Branches share the options and query documents of the union.
+func (c *Compiled) branches() []*Compiled {
+	if c.union == nil {
+		return []*Compiled{c}
+	}
+	branches := make([]*Compiled, len(c.union))
+	for i, b := range c.union {
+		branch := *c
+		branch.raw = b.raw
+		branch.segments = b.segments
+		branch.hasMulti = b.hasMulti
+		branch.hasFilter = b.hasFilter
+		branch.union = nil
+		branches[i] = &branch
+	}
+	return branches
+}
+
+// Returns the values matched by any branch. Branches that cannot be found are
+// skipped, unless none of the branches match.
+func (c *Compiled) getUnionValues(object interface{}) ([]interface{}, error) {
+	result := []interface{}{}
+	found := false
+	for _, branch := range c.branches() {
+		value, err := branch.getValues(object)
+		if err != nil {
+			if notFound(err) && !c.strictPaths {
+				continue
+			}
+			return nil, err
+		}
+		found = true
+		result = append(result, value...)
+	}
+	if !found {
+		return nil, &Error{Code: NotFound, Msg: "path not found"}
+	}
+	if c.dedupe {
+		result = dedupe(result)
+	}
+	return result, nil
+}
+
+// Sets a separate value for each branch of a union path. Values are keyed by
+// the raw path of the branch, e.g. "a.b" for the union "a.b | c.d". All keys
+// are checked against the branches, and every branch is set on a copy of the
+// object, before any value is set, so nothing is changed if a branch fails.
+func (c *Compiled) SetUnion(object interface{}, values map[string]interface{}) error {
+	branches := c.branches()
+	for key := range values {
+		found := false
+		for _, branch := range branches {
+			if branch.raw == key {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return &Error{Code: InvalidPath, Msg: fmt.Sprintf("path is not a branch of the union (%s)", key)}
+		}
+	}
+	root := reflect.ValueOf(object)
+	copied := deepCopy(root)
+	for _, target := range []*reflect.Value{&copied, &root} {
+		for _, branch := range branches {
+			value, ok := values[branch.raw]
+			if !ok {
+				continue
+			}
+			result, err := branch.set(*target, staticValue(value))
+			if err != nil {
+				return err
+			}
+			if result.IsValid() {
+				*target = result
+			}
+		}
+	}
+	return nil
+}
+
+func SetUnion(object interface{}, path string, values map[string]interface{}, options ...func(*Compiled)) error {
+	compiled, err := compileCached(path, options...)
+	if err != nil {
+		return err
+	}
+	return compiled.SetUnion(object, values)
+}
@@ -0,0 +1,262 @@
+package jsonpath
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestUnion(t *testing.T) {
+	type args struct {
+		object interface{}
+		path   string
+	}
+
+	tests := map[string][]struct {
+		name        string
+		args        args
+		want        interface{}
+		wantErr     bool
+		wantErrCode string
+		wantErrMsg  string
+	}{
+		"get": {
+			{
+				name: "two-branches",
+				args: args{
+					object: getData(),
+					path:   "$.key2.array[1] | $.key3.map.key1",
+				},
+				want: []interface{}{float64(456), "val1"},
+			},
+			{
+				name: "multi-match-branch",
+				args: args{
+					object: getData(),
+					path:   "key4[*].key1|key3.array[0]",
+				},
+				want: []interface{}{"val1", "val2", "val3", "val0"},
+			},
+			{
+				name: "pipe-in-brackets",
+				args: args{
+					object: map[string]interface{}{"a|b": "val1", "c": "val2"},
+					path:   "['a|b'] | c",
+				},
+				want: []interface{}{"val1", "val2"},
+			},
+			{
+				name: "pipe-in-dot-notation",
+				args: args{
+					object: map[string]interface{}{"a|b": "val1", "a": "val2", "b": "val3"},
+					path:   "$.a|b",
+				},
+				want: []interface{}{"val2", "val3"},
+			},
+			{
+				name: "missing-branch",
+				args: args{
+					object: getData(),
+					path:   "missing | key3.map.key2",
+				},
+				want: []interface{}{"val2"},
+			},
+		},
+		"errors": {
+			{
+				name: "no-branches-found",
+				args: args{
+					object: getData(),
+					path:   "missing | other",
+				},
+				wantErr:     true,
+				wantErrCode: NotFound,
+				wantErrMsg:  "path not found",
+			},
+			{
+				name: "pipe-key-in-dot-notation",
+				args: args{
+					object: map[string]interface{}{"a|b": "val1"},
+					path:   "$.a|b",
+				},
+				wantErr:     true,
+				wantErrCode: NotFound,
+				wantErrMsg:  "path not found",
+			},
+			{
+				name: "empty-branch",
+				args: args{
+					object: getData(),
+					path:   "key1 | ",
+				},
+				wantErr:     true,
+				wantErrCode: InvalidPath,
+				wantErrMsg:  "empty path at position 7",
+			},
+			{
+				name: "invalid-branch",
+				args: args{
+					object: getData(),
+					path:   "key1 | key2]",
+				},
+				wantErr:     true,
+				wantErrCode: InvalidPath,
+				wantErrMsg:  "missing opening bracket at position 11",
+			},
+		},
+	}
+
+	for groupName, group := range tests {
+		for _, tt := range group {
+			testName := fmt.Sprintf("%s-%s", groupName, tt.name)
+			if runTest != "" && testName != runTest {
+				continue
+			}
+			t.Run(testName, func(t *testing.T) {
+				got, err := Get(tt.args.object, tt.args.path)
+				if (err != nil) != tt.wantErr {
+					t.Errorf("Get() error = %v, wantErr %v", err, tt.wantErr)
+					return
+				}
+				if tt.wantErr {
+					if err.(*Error).Code != tt.wantErrCode {
+						t.Errorf("Get() errCode = %v, wantCode %v", err.(*Error).Code, tt.wantErrCode)
+					}
+					if !strings.Contains(err.Error(), tt.wantErrMsg) {
+						t.Errorf("Get() errMsg = %v, wantMsg %v", err.(*Error).Msg, tt.wantErrMsg)
+					}
+					return
+				}
+				if !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("Get() = %v, want %v", got, tt.want)
+				}
+			})
+		}
+	}
+}
+
+func TestUnionSet(t *testing.T) {
+	object := getData()
+	err := Set(object, "key1.key2 | key3.map.key1", newVal)
+	if err != nil {
+		t.Errorf("Set() error = %v", err)
+		return
+	}
+	got, err := Get(object, "key1.key2 | key3.map.key1")
+	if err != nil {
+		t.Errorf("Get() error = %v", err)
+		return
+	}
+	want := []interface{}{newVal, newVal}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Get() = %v, want %v", got, want)
+	}
+}
+
+func TestSetUnion(t *testing.T) {
+	tests := []struct {
+		name       string
+		path       string
+		object     func() map[string]interface{}
+		options    []func(*Compiled)
+		values     map[string]interface{}
+		want       interface{}
+		wantErr    bool
+		wantErrMsg string
+	}{
+		{
+			name: "disjoint-locations",
+			path: "a.b | c[1]",
+			values: map[string]interface{}{
+				"a.b":  "val1",
+				"c[1]": "val2",
+			},
+			want: map[string]interface{}{
+				"a": map[string]interface{}{"b": "val1"},
+				"c": []interface{}{nil, "val2"},
+			},
+		},
+		{
+			name: "partial",
+			path: "a.b | c",
+			values: map[string]interface{}{
+				"c": "val2",
+			},
+			want: map[string]interface{}{
+				"c": "val2",
+			},
+		},
+		{
+			name: "unknown-branch",
+			path: "a.b | c",
+			values: map[string]interface{}{
+				"a.b": "val1",
+				"d":   "val2",
+			},
+			want:       map[string]interface{}{},
+			wantErr:    true,
+			wantErrMsg: "path is not a branch of the union (d)",
+		},
+		{
+			name: "later-branch-fails",
+			path: "a.b | c.d",
+			object: func() map[string]interface{} {
+				return map[string]interface{}{"a": map[string]interface{}{"b": "old"}}
+			},
+			options: []func(*Compiled){EnableStrictPaths()},
+			values: map[string]interface{}{
+				"a.b": "val1",
+				"c.d": "val2",
+			},
+			want:       map[string]interface{}{"a": map[string]interface{}{"b": "old"}},
+			wantErr:    true,
+			wantErrMsg: "key does not exist",
+		},
+		{
+			name: "later-branch-type-mismatch",
+			path: "a.b | c[0]",
+			object: func() map[string]interface{} {
+				return map[string]interface{}{"c": map[string]int{}}
+			},
+			values: map[string]interface{}{
+				"a.b":  "val1",
+				"c[0]": "val2",
+			},
+			want:       map[string]interface{}{"c": map[string]int{}},
+			wantErr:    true,
+			wantErrMsg: "index",
+		},
+		{
+			name: "cyclic-struct",
+			path: "node.Name | other",
+			object: func() map[string]interface{} {
+				return map[string]interface{}{"node": getCyclicData("a")}
+			},
+			values: map[string]interface{}{
+				"node.Name": "b",
+				"other":     "val",
+			},
+			want: map[string]interface{}{"node": getCyclicData("b"), "other": "val"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			object := map[string]interface{}{}
+			if tt.object != nil {
+				object = tt.object()
+			}
+			err := SetUnion(object, tt.path, tt.values, tt.options...)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SetUnion() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr && !strings.Contains(err.Error(), tt.wantErrMsg) {
+				t.Errorf("SetUnion() errMsg = %v, wantMsg %v", err, tt.wantErrMsg)
+			}
+			if !reflect.DeepEqual(object, tt.want) {
+				t.Errorf("SetUnion() = %v, want %v", object, tt.want)
+			}
+		})
+	}
+}